@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+// action is something that can be done with a result. the script filter
+// picks one per item/modifier through the "action" variable, and alfred hands
+// it to the open command along with the item's arg.
+type action struct {
+	name string
+	desc string
+	// arg is what alfred passes to run for res. actions without one are only
+	// used by items that are not apps and cannot go on a modifier key.
+	arg func(res app) string
+	// subtitle overrides desc as the modifier subtitle when set
+	subtitle func(res app) string
+	run      func(ctx context.Context, arg string) error
+}
+
+var actions = map[string]action{}
+
+func registerAction(a action) {
+	if _, ok := actions[a.name]; ok {
+		panic(fmt.Sprintf("action %q registered twice", a.name))
+	}
+	actions[a.name] = a
+}
+
+// actionNames lists the actions that can go on a modifier key.
+func actionNames() []string {
+	names := make([]string, 0, len(actions))
+	for name, a := range actions {
+		if a.arg != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// modifierSettings maps each modifier key to the setting that picks its action.
+var modifierSettings = []struct {
+	key     aw.ModKey
+	setting string
+}{
+	{aw.ModCmd, "MOD_CMD"},
+	{aw.ModAlt, "MOD_ALT"},
+	{aw.ModCtrl, "MOD_CTRL"},
+	{aw.ModShift, "MOD_SHIFT"},
+	{aw.ModFn, "MOD_FN"},
+}
+
+// checkAction validates a MOD_* setting. actions register themselves in init
+// functions, so this has to look them up lazily.
+func checkAction(v string) error {
+	if v == "none" {
+		return nil
+	}
+	if a, ok := actions[v]; !ok || a.arg == nil {
+		return fmt.Errorf("unknown action %q, expected none or one of %s", v, strings.Join(actionNames(), ", "))
+	}
+	return nil
+}
+
+// encodeSpaces turns the + that url query encoding uses for spaces into
+// %20, for the url schemes (mailto, sms, things) that take + literally.
+func encodeSpaces(encoded string) string {
+	return strings.Replace(encoded, "+", "%20", -1)
+}
+
+func openURL(ctx context.Context, arg string) error {
+	return exec.CommandContext(ctx, "open", arg).Run()
+}
+
+// primaryURL is what ↩ opens, see PRIMARY_ACTION. otherURL is the alternative.
+func primaryURL(res app) string {
+	if config("PRIMARY_ACTION") == "browser" {
+		return res.URL
+	}
+	return storeURL(res.ID)
+}
+
+func otherURL(res app) string {
+	if config("PRIMARY_ACTION") == "browser" {
+		return storeURL(res.ID)
+	}
+	return res.URL
+}
+
+func init() {
+	registerAction(action{
+		name: "open",
+		desc: "Open",
+		arg:  primaryURL,
+		run:  openURL,
+	})
+	registerAction(action{
+		name: "open-other",
+		desc: "Open with whatever ↩ does not",
+		arg:  otherURL,
+		subtitle: func(res app) string {
+			if config("PRIMARY_ACTION") == "browser" {
+				return "Open in the App Store"
+			}
+			return "Open in browser"
+		},
+		run: openURL,
+	})
+	registerAction(action{
+		name: "open-store",
+		desc: "Open in the App Store",
+		arg:  func(res app) string { return storeURL(res.ID) },
+		run:  openURL,
+	})
+	registerAction(action{
+		name: "open-browser",
+		desc: "Open in browser",
+		arg:  func(res app) string { return res.URL },
+		run:  openURL,
+	})
+	registerAction(action{
+		name: "search-reviews",
+		desc: "Search the web for reviews",
+		arg: func(res app) string {
+			return "https://www.google.com/search?q=" + url.QueryEscape(`"`+res.Name+`" mac review`)
+		},
+		run: openURL,
+	})
+	registerAction(action{
+		name: "copy-url",
+		desc: "Copy store link",
+		arg:  func(res app) string { return res.URL },
+		run:  copyToClipboard,
+	})
+	registerAction(action{
+		name: "copy",
+		desc: "Copy",
+		run:  copyToClipboard,
+	})
+	registerAction(action{
+		name: "copy-bundle-id",
+		desc: "Copy bundle id",
+		arg:  func(res app) string { return res.BundleID },
+		run:  copyToClipboard,
+	})
+}
+
+func copyToClipboard(ctx context.Context, s string) error {
+	cmd := exec.CommandContext(ctx, "pbcopy")
+	cmd.Stdin = strings.NewReader(s)
+	return cmd.Run()
+}
+
+// appModifier adds a modifier to item that runs the named action on res. it
+// is disabled when the action has nothing to work with for res.
+func appModifier(item *aw.Item, key aw.ModKey, res app, name string) *aw.Modifier {
+	a := actions[name]
+	sub := a.desc
+	if a.subtitle != nil {
+		sub = a.subtitle(res)
+	}
+	arg := a.arg(res)
+	return item.NewModifier(key).
+		Valid(arg != "").
+		Arg(arg).
+		Subtitle(sub).
+		Var("action", name).
+		Var("app_id", fmt.Sprint(res.ID)).
+		Var("app_name", res.Name).
+		Var("app_developer", res.Developer)
+}
+
+// addModifiers lays the configured actions out on item's modifier keys.
+func addModifiers(item *aw.Item, res app) {
+	for _, m := range modifierSettings {
+		if name := config(m.setting); name != "" && name != "none" {
+			appModifier(item, m.key, res, name)
+		}
+	}
+}
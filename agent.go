@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const agentLabel = bundleID + ".refresh"
+
+var agentPlist = template.Must(template.New("agent").Funcs(template.FuncMap{
+	"xml": template.HTMLEscapeString,
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{.Label | xml}}</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>{{.Program | xml}}</string>
+		<string>refresh</string>
+	</array>
+	<key>StartInterval</key>
+	<integer>{{.Interval}}</integer>
+	<key>RunAtLoad</key>
+	<false/>
+	<key>ProcessType</key>
+	<string>Background</string>
+	<key>LowPriorityIO</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>{{.Log | xml}}</string>
+	<key>StandardErrorPath</key>
+	<string>{{.Log | xml}}</string>
+	<key>EnvironmentVariables</key>
+	<dict>{{range $k, $v := .Env}}
+		<key>{{$k | xml}}</key>
+		<string>{{$v | xml}}</string>{{end}}
+	</dict>
+</dict>
+</plist>
+`))
+
+func init() {
+	register(command{
+		name:  "agent",
+		usage: "agent install|uninstall|status",
+		desc:  "manage the launch agent that runs refresh in the background",
+		run:   agentCmd,
+	})
+}
+
+func agentCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: agent install|uninstall|status")
+	}
+	switch args[0] {
+	case "install":
+		return agentInstall(ctx, args[1:])
+	case "uninstall":
+		return agentUninstall(ctx)
+	case "status":
+		return agentStatus(ctx)
+	}
+	return fmt.Errorf("unknown agent command %q", args[0])
+}
+
+func agentPlistPath() string {
+	return filepath.Join(homeDir(), "Library/LaunchAgents", agentLabel+".plist")
+}
+
+func launchdDomain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+func agentInstall(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("agent install", flag.ContinueOnError)
+	interval := fs.Duration("interval", time.Hour*6, "how often to refresh")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// launchd starts the job with an empty environment, so hand it the
+	// workflow's dirs and the settings as they are right now. credentials
+	// stay out of the plist, the agent reads them from the keychain
+	env := map[string]string{
+		"alfred_workflow_data":  dataDir(),
+		"alfred_workflow_cache": cacheDir(),
+	}
+	for _, s := range settings {
+		if v := os.Getenv(s.key); v != "" && !isSecret(s.key) {
+			env[s.key] = v
+		}
+	}
+	if err := os.MkdirAll(cacheDir(), os.ModePerm); err != nil {
+		return err
+	}
+	var plist strings.Builder
+	err = agentPlist.Execute(&plist, map[string]interface{}{
+		"Label":    agentLabel,
+		"Program":  exe,
+		"Interval": int(interval.Seconds()),
+		"Log":      filepath.Join(cacheDir(), "agent.log"),
+		"Env":      env,
+	})
+	if err != nil {
+		return err
+	}
+	// reinstalling replaces whatever was loaded before
+	agentUninstall(ctx)
+	if err := os.MkdirAll(filepath.Dir(agentPlistPath()), os.ModePerm); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(agentPlistPath(), []byte(plist.String()), 0600); err != nil {
+		return err
+	}
+	if err := launchctl(ctx, "bootstrap", launchdDomain(), agentPlistPath()); err != nil {
+		return err
+	}
+	fmt.Printf("installed %s, refreshing every %s\n", agentLabel, interval)
+	return nil
+}
+
+func agentUninstall(ctx context.Context) error {
+	if _, err := os.Stat(agentPlistPath()); os.IsNotExist(err) {
+		return nil
+	}
+	if err := launchctl(ctx, "bootout", launchdDomain()+"/"+agentLabel); err != nil {
+		debug("%s", err.Error())
+	}
+	return os.Remove(agentPlistPath())
+}
+
+func agentStatus(ctx context.Context) error {
+	if _, err := os.Stat(agentPlistPath()); os.IsNotExist(err) {
+		fmt.Println("not installed")
+		return nil
+	}
+	loaded := exec.CommandContext(ctx, "launchctl", "print", launchdDomain()+"/"+agentLabel).Run() == nil
+	fmt.Printf("installed: %s\nloaded: %t\n", agentPlistPath(), loaded)
+	if s, ok := loadRefreshState(); ok {
+		fmt.Printf("last refresh: %s (%s)\n", s.Last.Format(time.RFC1123), relativeTime(s.Last))
+		for job, err := range s.Errors {
+			fmt.Printf("  %s failed: %s\n", job, err)
+		}
+	} else {
+		fmt.Println("last refresh: never")
+	}
+	return nil
+}
+
+func launchctl(ctx context.Context, args ...string) error {
+	out, err := exec.CommandContext(ctx, "launchctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl %s: %s: %s", args[0], err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/deanishe/awgo"
+)
+
+// allProviders is what from:all searches: every enabled provider at once,
+// with the results ranked together.
+const allProviders = "all"
+
+type aggregate struct{}
+
+func (aggregate) name() string  { return allProviders }
+func (aggregate) label() string { return "All" }
+
+// search fans q out to every enabled provider. a provider failing only
+// loses its own results, unless they all fail. the error still goes back
+// with the rest, so search shows them but does not cache them.
+func (aggregate) search(ctx context.Context, q query) ([]app, error) {
+	enabled := enabledProviders()
+	lists := make([][]app, len(enabled))
+	errs := make([]error, len(enabled))
+	var wg sync.WaitGroup
+	for i, p := range enabled {
+		wg.Add(1)
+		go func(i int, p provider) {
+			defer wg.Done()
+			lists[i], errs[i] = p.search(ctx, q)
+		}(i, p)
+	}
+	wg.Wait()
+	var (
+		ranked []rankedApp
+		failed error
+		seen   = map[int64]bool{}
+	)
+	for i, list := range lists {
+		if errs[i] != nil {
+			// a provider can fail part way and still have results
+			debug("provider %s failed: %s", enabled[i].name(), errs[i].Error())
+			failed = errs[i]
+		}
+		for pos, res := range list {
+			if seen[res.ID] {
+				continue
+			}
+			seen[res.ID] = true
+			ranked = append(ranked, rankedApp{res, relevance(res, q.term(), pos)})
+		}
+	}
+	if len(ranked) == 0 && failed != nil {
+		return nil, failed
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+	results := make([]app, len(ranked))
+	for i, r := range ranked {
+		results[i] = r.app
+	}
+	return results, failed
+}
+
+type rankedApp struct {
+	app
+	score float64
+}
+
+// relevance scores res for term on a scale shared by every provider: how
+// well the name matches, where the provider itself ranked it, and (for
+// catalogs that have them) how many ratings back it up.
+func relevance(res app, term string, pos int) float64 {
+	name := baseName(res.Name)
+	term = normalizeQuery(term)
+	var score float64
+	switch {
+	case name == term:
+		score += 3
+	case strings.HasPrefix(name, term):
+		score += 2
+	case strings.Contains(name, term):
+		score += 1
+	}
+	score += 1 / float64(1+pos)
+	score += math.Log10(float64(res.NumRatings)+1) / 5
+	return score
+}
+
+// iconProvider is implemented by providers whose results come without
+// artwork, to show something better than the workflow icon.
+type iconProvider interface {
+	icon() *aw.Icon
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the amp api is what apps.apple.com itself talks to. it needs a bearer
+// token, which the store web pages embed in their config meta tag.
+const (
+	ampBaseURL  = "https://amp-api.apps.apple.com/v1/"
+	ampTokenTTL = time.Hour * 12
+)
+
+var ampConfigMeta = regexp.MustCompile(`<meta name="web-experience-app/config/environment" content="([^"]+)"`)
+
+// ampToken returns the bearer token, kept in the keychain as its expiry
+// (unix time) and the token separated by a space.
+func ampToken(ctx context.Context) (string, error) {
+	if parts := strings.SplitN(secret(ampTokenSecret), " ", 2); len(parts) == 2 {
+		if expires, err := strconv.ParseInt(parts[0], 10, 64); err == nil && time.Now().Unix() < expires {
+			return parts[1], nil
+		}
+	}
+	token, err := fetchAMPToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	expires := time.Now().Add(ampTokenTTL).Unix()
+	if err := setSecret(ctx, ampTokenSecret, fmt.Sprintf("%d %s", expires, token)); err != nil {
+		debug("%s", err.Error())
+	}
+	return token, nil
+}
+
+func fetchAMPToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequest("GET", "https://apps.apple.com/"+config("COUNTRY")+"/charts/mac", http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	debug("bootstrapping amp token: %s", req.URL.String())
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	m := ampConfigMeta.FindSubmatch(body)
+	if m == nil {
+		return "", fmt.Errorf("no config meta tag found on the store page")
+	}
+	raw, err := url.QueryUnescape(html.UnescapeString(string(m[1])))
+	if err != nil {
+		return "", err
+	}
+	var cfg struct {
+		MediaAPI struct {
+			Token string `json:"token"`
+		} `json:"MEDIA_API"`
+	}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return "", err
+	}
+	if cfg.MediaAPI.Token == "" {
+		return "", fmt.Errorf("store page config has no api token")
+	}
+	return cfg.MediaAPI.Token, nil
+}
+
+// amp sends an authenticated get to path under the amp api and decodes the
+// response into v. a rejected token is thrown away so the next call
+// bootstraps a new one.
+func amp(ctx context.Context, path string, params url.Values, v interface{}) error {
+	defer timed("api")()
+	token, err := ampToken(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("GET", ampBaseURL+path+"?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Origin", "https://apps.apple.com")
+	debug("sending request: %s %s", req.Method, req.URL.String())
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		if err := deleteSecret(ctx, ampTokenSecret); err != nil {
+			debug("%s", err.Error())
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the BACKEND setting picks where search results and lookups come from. the
+// legacy itunes api is the default; "amp" uses the api apps.apple.com is
+// built on, which also knows editorial notes and in-app purchases. whenever
+// amp fails for a reason other than rate limiting the itunes api is asked
+// instead.
+
+// ampPlatforms maps search api entities to the amp platform and the key of
+// its platformAttributes.
+var ampPlatforms = map[string]struct{ platform, attributes string }{
+	"macSoftware": {"mac", "osx"},
+	"software":    {"iphone", "ios"},
+}
+
+type ampApp struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Name                   string   `json:"name"`
+		ArtistName             string   `json:"artistName"`
+		URL                    string   `json:"url"`
+		Genre                  string   `json:"genreDisplayName"`
+		GenreNames             []string `json:"genreNames"`
+		ContentRatingsBySystem struct {
+			AppsApple struct {
+				Name string `json:"name"`
+			} `json:"appsApple"`
+		} `json:"contentRatingsBySystem"`
+		UserRating struct {
+			Value       float64 `json:"value"`
+			RatingCount int     `json:"ratingCount"`
+		} `json:"userRating"`
+		FileSizeByDevice   map[string]int64             `json:"fileSizeByDevice"`
+		PlatformAttributes map[string]ampPlatformFields `json:"platformAttributes"`
+	} `json:"attributes"`
+	Relationships struct {
+		TopInApps struct {
+			Data []struct {
+				Attributes struct {
+					Name   string     `json:"name"`
+					Offers []ampOffer `json:"offers"`
+				} `json:"attributes"`
+			} `json:"data"`
+		} `json:"top-in-apps"`
+	} `json:"relationships"`
+}
+
+type ampPlatformFields struct {
+	BundleID   string `json:"bundleId"`
+	SellerName string `json:"seller"`
+	Artwork    struct {
+		URL string `json:"url"`
+	} `json:"artwork"`
+	Offers         []ampOffer `json:"offers"`
+	GameCenter     bool       `json:"isGameCenterEnabled"`
+	Languages      []string   `json:"languageList"`
+	EditorialNotes struct {
+		Standard string `json:"standard"`
+		Short    string `json:"short"`
+	} `json:"editorialNotes"`
+	VersionHistory []struct {
+		Version     string `json:"versionDisplay"`
+		ReleaseDate string `json:"releaseDate"`
+	} `json:"versionHistory"`
+}
+
+type ampOffer struct {
+	Price          float64 `json:"price"`
+	PriceFormatted string  `json:"priceFormatted"`
+	CurrencyCode   string  `json:"currencyCode"`
+}
+
+func (o ampOffer) formatted() string {
+	if o.Price == 0 {
+		return "Free"
+	}
+	return o.PriceFormatted
+}
+
+// toApp flattens an amp catalog entry into the same shape the itunes api
+// returns.
+func (a ampApp) toApp(entity string) app {
+	attrs := a.Attributes
+	pa := attrs.PlatformAttributes[ampPlatforms[entity].attributes]
+	id, _ := strconv.ParseInt(a.ID, 10, 64)
+	res := app{
+		ID:         id,
+		Name:       attrs.Name,
+		BundleID:   pa.BundleID,
+		URL:        attrs.URL,
+		Rating:     attrs.UserRating.Value,
+		NumRatings: attrs.UserRating.RatingCount,
+		Developer:  attrs.ArtistName,
+		Seller:     pa.SellerName,
+		AgeRating:  attrs.ContentRatingsBySystem.AppsApple.Name,
+		Genre:      attrs.Genre,
+		Genres:     attrs.GenreNames,
+		Languages:  pa.Languages,
+		GameCenter: pa.GameCenter,
+		Kind:       "software",
+	}
+	if entity == "macSoftware" {
+		res.Kind = "mac-software"
+	}
+	res.Artwork = strings.NewReplacer("{w}", "512", "{h}", "512", "{c}", "bb", "{f}", "png").Replace(pa.Artwork.URL)
+	if n := attrs.FileSizeByDevice["universal"]; n > 0 {
+		res.FileSize = fmt.Sprint(n)
+	}
+	if len(pa.Offers) > 0 {
+		res.Price = pa.Offers[0].Price
+		res.PriceFmt = pa.Offers[0].formatted()
+		res.Currency = pa.Offers[0].CurrencyCode
+	}
+	if len(pa.VersionHistory) > 0 {
+		res.Version = pa.VersionHistory[0].Version
+		res.Updated, _ = time.Parse("2006-01-02", pa.VersionHistory[0].ReleaseDate)
+	}
+	res.EditorialNote = pa.EditorialNotes.Standard
+	if res.EditorialNote == "" {
+		res.EditorialNote = pa.EditorialNotes.Short
+	}
+	for _, iap := range a.Relationships.TopInApps.Data {
+		name := iap.Attributes.Name
+		if len(iap.Attributes.Offers) > 0 {
+			name += " (" + iap.Attributes.Offers[0].formatted() + ")"
+		}
+		res.InAppPurchases = append(res.InAppPurchases, name)
+	}
+	return res
+}
+
+func ampParams(entity string) url.Values {
+	params := url.Values{}
+	params.Set("platform", ampPlatforms[entity].platform)
+	params.Set("extend", "editorialNotes")
+	params.Set("include", "top-in-apps")
+	params.Set("l", "en-US")
+	return params
+}
+
+func ampSearch(ctx context.Context, country, entity, term string, limit int) ([]app, error) {
+	params := ampParams(entity)
+	params.Set("term", term)
+	params.Set("types", "apps")
+	params.Set("limit", strconv.Itoa(limit))
+	var body struct {
+		Results struct {
+			Apps struct {
+				Data []ampApp `json:"data"`
+			} `json:"apps"`
+		} `json:"results"`
+	}
+	if err := amp(ctx, fmt.Sprintf("catalog/%s/search", country), params, &body); err != nil {
+		return nil, err
+	}
+	results := make([]app, len(body.Results.Apps.Data))
+	for i, a := range body.Results.Apps.Data {
+		results[i] = a.toApp(entity)
+	}
+	return results, nil
+}
+
+func ampLookup(ctx context.Context, country string, ids []string) ([]app, error) {
+	params := ampParams("macSoftware")
+	params.Set("ids", strings.Join(ids, ","))
+	var body struct {
+		Data []ampApp `json:"data"`
+	}
+	if err := amp(ctx, fmt.Sprintf("catalog/%s/apps", country), params, &body); err != nil {
+		return nil, err
+	}
+	results := make([]app, len(body.Data))
+	for i, a := range body.Data {
+		entity := "macSoftware"
+		if _, ok := a.Attributes.PlatformAttributes["osx"]; !ok {
+			entity = "software"
+		}
+		results[i] = a.toApp(entity)
+	}
+	return results, nil
+}
+
+// searchBackend runs one search against the configured backend. amp cannot
+// restrict matching to a field, so attribute searches always go to itunes.
+func searchBackend(ctx context.Context, country, entity, term, attribute string, limit int) ([]app, error) {
+	if config("BACKEND") == "amp" && attribute == "" {
+		results, err := ampSearch(ctx, country, entity, term, limit)
+		if err == nil || isRateLimit(err) {
+			return results, err
+		}
+		debug("amp search failed, falling back to itunes: %s", err.Error())
+	}
+	params := url.Values{}
+	params.Set("media", "software")
+	params.Set("entity", entity)
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("country", country)
+	params.Set("term", term)
+	if attribute != "" {
+		params.Set("attribute", attribute)
+	}
+	return itunes(ctx, "search", params)
+}
+
+// lookupBackend looks ids up in country with the configured backend.
+func lookupBackend(ctx context.Context, country string, ids []string) ([]app, error) {
+	if config("BACKEND") == "amp" {
+		results, err := ampLookup(ctx, country, ids)
+		if err == nil || isRateLimit(err) {
+			return results, err
+		}
+		debug("amp lookup failed, falling back to itunes: %s", err.Error())
+	}
+	params := url.Values{}
+	params.Set("id", strings.Join(ids, ","))
+	params.Set("country", country)
+	return itunes(ctx, "lookup", params)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // artwork is sometimes served as jpeg
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+// badges are drawn onto a copy of an app's icon so its status shows at a
+// glance. only the most important one is drawn: update, then installed,
+// then paid.
+const (
+	badgeUpdate    = "update"
+	badgeInstalled = "installed"
+	badgePaid      = "paid"
+)
+
+var badgeNames = []string{badgeUpdate, badgeInstalled, badgePaid}
+
+var badgeColors = map[string]color.RGBA{
+	badgeUpdate:    {0x0a, 0x84, 0xff, 0xff},
+	badgeInstalled: {0x30, 0xd1, 0x58, 0xff},
+	badgePaid:      {0xff, 0x9f, 0x0a, 0xff},
+}
+
+// badgeFor picks the badge res gets, "" for none.
+func badgeFor(res app) string {
+	enabled := map[string]bool{}
+	for _, b := range strings.Split(config("ICON_BADGES"), ",") {
+		enabled[strings.TrimSpace(b)] = true
+	}
+	if res.Source != "" {
+		return ""
+	}
+	installed, isInstalled := installedVersion(res.BundleID)
+	switch {
+	case enabled[badgeUpdate] && isInstalled && installed != "" && versionLess(installed, res.Version):
+		return badgeUpdate
+	case enabled[badgeInstalled] && isInstalled:
+		return badgeInstalled
+	case enabled[badgePaid] && res.Price > 0:
+		return badgePaid
+	}
+	return ""
+}
+
+// badgedIcon returns icon with res's badge drawn on, the badged copy being
+// kept next to the original. anything going wrong leaves icon as it was.
+func badgedIcon(icon *aw.Icon, res app) *aw.Icon {
+	badge := badgeFor(res)
+	if badge == "" || icon == nil || icon.Type != aw.IconTypeImage {
+		return icon
+	}
+	ext := filepath.Ext(icon.Value)
+	filename := strings.TrimSuffix(icon.Value, ext) + "-" + badge + ".png"
+	if _, err := os.Stat(filename); err == nil {
+		return &aw.Icon{Type: aw.IconTypeImage, Value: filename}
+	}
+	if err := drawBadge(icon.Value, filename, badge); err != nil {
+		debug("failed to badge %s: %s", icon.Value, err.Error())
+		return icon
+	}
+	return &aw.Icon{Type: aw.IconTypeImage, Value: filename}
+}
+
+func drawBadge(src, dst, badge string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return err
+	}
+	b := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), img, b.Min, draw.Src)
+
+	size := float64(b.Dx())
+	r := size * 0.17
+	cx, cy := size-r-size*0.02, size-r-size*0.02
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	fillCircle(canvas, cx, cy, r+size*0.02, white)
+	fillCircle(canvas, cx, cy, r, badgeColors[badge])
+	w := r * 0.22
+	switch badge {
+	case badgeInstalled:
+		// a check mark
+		thickLine(canvas, cx-r*0.45, cy, cx-r*0.1, cy+r*0.38, w, white)
+		thickLine(canvas, cx-r*0.1, cy+r*0.38, cx+r*0.5, cy-r*0.35, w, white)
+	case badgeUpdate:
+		// an arrow pointing down
+		thickLine(canvas, cx, cy-r*0.5, cx, cy+r*0.45, w, white)
+		thickLine(canvas, cx-r*0.4, cy+r*0.05, cx, cy+r*0.45, w, white)
+		thickLine(canvas, cx+r*0.4, cy+r*0.05, cx, cy+r*0.45, w, white)
+	case badgePaid:
+		// a price tag: a diamond with a hole punched in it
+		for _, d := range []float64{0.5, 0.35, 0.2} {
+			fillDiamond(canvas, cx, cy, r*d, white)
+		}
+		fillCircle(canvas, cx, cy-r*0.25, r*0.1, badgeColors[badge])
+	}
+
+	out, err := os.Create(dst + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(out, canvas); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dst)
+}
+
+func fillCircle(img *image.RGBA, cx, cy, r float64, c color.RGBA) {
+	for y := int(cy - r); y <= int(cy+r)+1; y++ {
+		for x := int(cx - r); x <= int(cx+r)+1; x++ {
+			if math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy) <= r {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+func fillDiamond(img *image.RGBA, cx, cy, r float64, c color.RGBA) {
+	for y := int(cy - r); y <= int(cy+r)+1; y++ {
+		for x := int(cx - r); x <= int(cx+r)+1; x++ {
+			if math.Abs(float64(x)+0.5-cx)+math.Abs(float64(y)+0.5-cy) <= r {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// thickLine strokes a line w wide with round ends, by stamping circles
+// along it.
+func thickLine(img *image.RGBA, x0, y0, x1, y1, w float64, c color.RGBA) {
+	steps := int(math.Hypot(x1-x0, y1-y0)) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		fillCircle(img, x0+(x1-x0)*t, y0+(y1-y0)*t, w/2, c)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+const blocklistFile = "blocklist.json"
+
+// blocklist hides apps and developers from every list of results, for clone
+// factories and the like.
+type blocklist struct {
+	Apps       map[string]string `json:"apps"` // track id -> name, for listing
+	Developers []string          `json:"developers"`
+}
+
+func loadBlocklist() (*blocklist, error) {
+	b := &blocklist{Apps: map[string]string{}}
+	c := aw.NewCache(dataDir())
+	if !c.Exists(blocklistFile) {
+		return b, nil
+	}
+	if err := c.LoadJSON(blocklistFile, b); err != nil {
+		return b, err
+	}
+	if b.Apps == nil {
+		b.Apps = map[string]string{}
+	}
+	return b, nil
+}
+
+func (b *blocklist) save() error {
+	return aw.NewCache(dataDir()).StoreJSON(blocklistFile, b)
+}
+
+func (b *blocklist) blocks(res app) bool {
+	if _, ok := b.Apps[fmt.Sprint(res.ID)]; ok {
+		return true
+	}
+	for _, d := range b.Developers {
+		if strings.EqualFold(d, res.Developer) || strings.EqualFold(d, res.Seller) {
+			return true
+		}
+	}
+	return false
+}
+
+// unblocked drops blocked apps from results.
+func unblocked(results []app) []app {
+	keep := unblockedIndexes(results)
+	if len(keep) == len(results) {
+		return results
+	}
+	out := results[:0]
+	for _, i := range keep {
+		out = append(out, results[i])
+	}
+	return out
+}
+
+// unblockedIndexes returns the positions in results of the apps that are
+// not blocked, for callers that keep other slices (snippets, view times) in
+// step with results.
+func unblockedIndexes(results []app) []int {
+	b, err := loadBlocklist()
+	if err != nil {
+		debug("failed to load blocklist: %s", err.Error())
+	}
+	keep := make([]int, 0, len(results))
+	for i, res := range results {
+		if err != nil || !b.blocks(res) {
+			keep = append(keep, i)
+		}
+	}
+	return keep
+}
+
+func init() {
+	registerAction(action{
+		name: "block-app",
+		desc: "Never show this app again",
+		arg:  func(res app) string { return fmt.Sprint(res.ID) },
+		run: func(ctx context.Context, arg string) error {
+			return blocklistCmd(ctx, []string{"add-app", arg})
+		},
+	})
+	registerAction(action{
+		name:     "block-developer",
+		desc:     "Never show apps by this developer again",
+		arg:      func(res app) string { return res.Developer },
+		subtitle: func(res app) string { return "Never show apps by " + res.Developer + " again" },
+		run: func(ctx context.Context, arg string) error {
+			return blocklistCmd(ctx, []string{"add-developer", arg})
+		},
+	})
+	register(command{
+		name:  "blocklist",
+		usage: "blocklist list|add-app|remove-app|add-developer|remove-developer",
+		desc:  "manage the apps and developers hidden from results",
+		run:   blocklistCmd,
+	})
+}
+
+func blocklistCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	b, err := loadBlocklist()
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		ids := make([]string, 0, len(b.Apps))
+		for id := range b.Apps {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			fmt.Printf("app\t%s\t%s\n", id, b.Apps[id])
+		}
+		for _, d := range b.Developers {
+			fmt.Printf("developer\t%s\n", d)
+		}
+		return nil
+	case "add-app":
+		ids, err := parseIDs(args[1:])
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			// the action knows the name already, from the command line
+			// it has to be looked up
+			name := os.Getenv("app_name")
+			if name == "" || len(ids) > 1 {
+				if res, err := lookup(ctx, id); err == nil && res != nil {
+					name = res.Name
+				}
+			}
+			b.Apps[strconv.FormatInt(id, 10)] = name
+		}
+	case "remove-app":
+		for _, id := range args[1:] {
+			delete(b.Apps, id)
+		}
+	case "add-developer":
+		name := strings.TrimSpace(strings.Join(args[1:], " "))
+		if name == "" {
+			return fmt.Errorf("usage: blocklist add-developer <name>")
+		}
+		for _, d := range b.Developers {
+			if strings.EqualFold(d, name) {
+				return nil
+			}
+		}
+		b.Developers = append(b.Developers, name)
+	case "remove-developer":
+		name := strings.TrimSpace(strings.Join(args[1:], " "))
+		kept := b.Developers[:0]
+		for _, d := range b.Developers {
+			if !strings.EqualFold(d, name) {
+				kept = append(kept, d)
+			}
+		}
+		b.Developers = kept
+	default:
+		return fmt.Errorf("unknown blocklist command %q", args[0])
+	}
+	return b.save()
+}
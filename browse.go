@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+// gameGenres are the mac app store's sub-genres of Games (12006).
+var gameGenres = []struct {
+	name string
+	id   int
+}{
+	{"Action", 12201},
+	{"Adventure", 12202},
+	{"Arcade", 12203},
+	{"Board", 12204},
+	{"Card", 12205},
+	{"Casino", 12206},
+	{"Dice", 12207},
+	{"Educational", 12208},
+	{"Family", 12209},
+	{"Kids", 12210},
+	{"Music", 12211},
+	{"Puzzle", 12212},
+	{"Racing", 12213},
+	{"Role Playing", 12214},
+	{"Simulation", 12215},
+	{"Sports", 12216},
+	{"Strategy", 12217},
+	{"Trivia", 12218},
+	{"Word", 12219},
+}
+
+func genreSlug(name string) string {
+	return strings.ToLower(strings.Replace(name, " ", "-", -1))
+}
+
+// gamesFeedback browses games. "games:" lists the sub-genres, "games:puzzle"
+// the top free puzzle games. a chart name among the other terms
+// ("games:puzzle top-paid") picks a different chart.
+func gamesFeedback(ctx context.Context, fb *aw.Feedback, genre string, terms []string) error {
+	genre = strings.ToLower(genre)
+	for _, g := range gameGenres {
+		if genreSlug(g.name) != genre {
+			continue
+		}
+		name := "top-free"
+		for _, t := range terms {
+			if _, ok := charts[t]; ok {
+				name = t
+			}
+		}
+		results, err := chartApps(ctx, name, g.id)
+		if err != nil {
+			return err
+		}
+		appendApps(ctx, fb, results)
+		return nil
+	}
+	n := len(fb.Items)
+	for _, g := range gameGenres {
+		slug := genreSlug(g.name)
+		if genre != "" && !strings.HasPrefix(slug, genre) {
+			continue
+		}
+		fb.NewItem(g.name).
+			Subtitle("top " + strings.ToLower(g.name) + " games · add top-paid or top-grossing for other charts").
+			Autocomplete("games:" + slug + " ").
+			Icon(aw.IconGroup).
+			Valid(false)
+	}
+	if len(fb.Items) == n {
+		fb.NewItem(fmt.Sprintf("no game genre matches %q", genre)).
+			Icon(aw.IconWarning).
+			Valid(false)
+	}
+	return nil
+}
+
+// chartApps returns the apps on a chart as full lookup records, so they can
+// be rendered like search results.
+func chartApps(ctx context.Context, name string, genre int) ([]app, error) {
+	entries, err := chart(ctx, name, genre)
+	if err != nil {
+		return nil, err
+	}
+	if limit := configInt("RESULT_LIMIT"); len(entries) > limit {
+		entries = entries[:limit]
+	}
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	found, err := lookupMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	// lookup does not keep the order it was asked in
+	byID := map[int64]app{}
+	for _, res := range found {
+		byID[res.ID] = res
+	}
+	results := make([]app, 0, len(found))
+	for _, id := range ids {
+		if res, ok := byID[id]; ok {
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// apple is understood to allow about 20 itunes api calls a minute. every
+// process (searches as they are typed, prefetch, the refresh agent) takes
+// its calls from one token bucket, kept in a file under an flock so they
+// share it.
+const (
+	bucketFile = "ratelimit.bucket"
+	// bucketBurst is how many calls can go out back to back before they
+	// are spaced out to the configured rate. it is raised to a search's
+	// fan-out when that is bigger, so one search never throttles itself.
+	bucketBurst = 10
+)
+
+// tokenWait is the longest a call waits for a token before giving up with
+// errThrottled. left at 0 a search waits as long as its last request would
+// take to get a token from an empty bucket, background commands raise it.
+var tokenWait time.Duration
+
+// searchFanOut is how many requests one app store search sends at once, one
+// per platform in every storefront.
+func searchFanOut() int {
+	n := len(searchCountries()) * len(searchEntities())
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+type bucketState struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// takeToken blocks until an api call may be made, or returns errThrottled
+// when that would take longer than tokenWait.
+func takeToken(ctx context.Context) error {
+	budget := tokenWait
+	if budget == 0 {
+		if perMinute := configInt("API_RATE_LIMIT"); perMinute > 0 {
+			// a second on top, for the requests racing each other for tokens
+			budget = time.Duration(searchFanOut())*time.Minute/time.Duration(perMinute) + time.Second
+		}
+	}
+	deadline := time.Now().Add(budget)
+	for {
+		wait, err := tryTakeToken()
+		if err != nil {
+			// a broken bucket file should not stop searches
+			debug("rate limiter unavailable: %s", err.Error())
+			return nil
+		}
+		if wait == 0 {
+			return nil
+		}
+		if time.Now().Add(wait).After(deadline) {
+			debug("no api call available for %s, not sending request", wait)
+			return errThrottled
+		}
+		debug("waiting %s for the rate limiter", wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// tryTakeToken takes a token if there is one, otherwise it says how long
+// until there will be.
+func tryTakeToken() (time.Duration, error) {
+	perMinute := float64(configInt("API_RATE_LIMIT"))
+	if perMinute <= 0 {
+		return 0, nil
+	}
+	if err := os.MkdirAll(cacheDir(), os.ModePerm); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(filepath.Join(cacheDir(), bucketFile), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return 0, err
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	burst := float64(bucketBurst)
+	if n := float64(searchFanOut()); n > burst {
+		burst = n
+	}
+	if perMinute < burst {
+		burst = perMinute
+	}
+	s := bucketState{Tokens: burst, Updated: time.Now()}
+	if data, err := ioutil.ReadAll(f); err == nil && len(data) > 0 {
+		if err := json.Unmarshal(data, &s); err != nil {
+			debug("resetting corrupt rate limiter state: %s", err.Error())
+			s = bucketState{Tokens: burst, Updated: time.Now()}
+		}
+	}
+	now := time.Now()
+	s.Tokens += now.Sub(s.Updated).Minutes() * perMinute
+	if s.Tokens > burst {
+		s.Tokens = burst
+	}
+	s.Updated = now
+	var wait time.Duration
+	if s.Tokens >= 1 {
+		s.Tokens--
+	} else {
+		wait = time.Duration((1 - s.Tokens) / perMinute * float64(time.Minute))
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.Truncate(0); err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return 0, err
+	}
+	return wait, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// setenv sets key for the rest of the test.
+func setenv(t *testing.T, key, value string) {
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// useTempCache points the cache dir at a directory removed after the test.
+func useTempCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	setenv(t, "alfred_workflow_cache", dir)
+}
+
+// drain takes tokens until one has to be waited for, returning how many
+// were free and the wait for the next.
+func drain(t *testing.T) (int, time.Duration) {
+	for n := 0; n < 100; n++ {
+		wait, err := tryTakeToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wait > 0 {
+			return n, wait
+		}
+	}
+	t.Fatal("bucket never ran out")
+	return 0, 0
+}
+
+func TestTryTakeToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     string
+		platforms string
+		extra     string
+		burst     int
+	}{
+		{"one request a search", "20", "mac", "", bucketBurst},
+		{"burst raised to the fan-out", "20", "mac,ios", "gb,de,fr,jp,ca", 12},
+		{"burst capped by the rate", "4", "mac", "", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempCache(t)
+			setenv(t, "API_RATE_LIMIT", tt.limit)
+			setenv(t, "PLATFORMS", tt.platforms)
+			setenv(t, "EXTRA_COUNTRIES", tt.extra)
+			n, wait := drain(t)
+			if n != tt.burst {
+				t.Errorf("took %d tokens back to back, want %d", n, tt.burst)
+			}
+			// the next token is a whole interval away, less the time the
+			// test has taken so far
+			interval := time.Minute / time.Duration(configInt("API_RATE_LIMIT"))
+			if wait > interval || wait < interval-time.Second {
+				t.Errorf("wait for the next token = %s, want about %s", wait, interval)
+			}
+		})
+	}
+}
+
+func TestTryTakeTokenDisabled(t *testing.T) {
+	useTempCache(t)
+	setenv(t, "API_RATE_LIMIT", "0")
+	for i := 0; i < 50; i++ {
+		if wait, err := tryTakeToken(); err != nil || wait != 0 {
+			t.Fatalf("tryTakeToken() = %s, %v with the limit off", wait, err)
+		}
+	}
+}
+
+func TestTakeTokenThrottles(t *testing.T) {
+	useTempCache(t)
+	setenv(t, "API_RATE_LIMIT", "20")
+	setenv(t, "PLATFORMS", "mac")
+	setenv(t, "EXTRA_COUNTRIES", "")
+	defer func(old time.Duration) { tokenWait = old }(tokenWait)
+	tokenWait = time.Millisecond
+	drain(t)
+	if err := takeToken(context.Background()); err != errThrottled {
+		t.Errorf("takeToken() on an empty bucket = %v, want errThrottled", err)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+// cacheBackend stores re-fetchable data: search results, charts, catalogs
+// and the like. its methods mirror awgo's Cache, which is the file backend.
+// state that coordinates separate runs of the workflow (rate limit backoff,
+// refresh and prefetch bookkeeping) always lives in files, whatever the
+// CACHE_BACKEND setting says.
+type cacheBackend interface {
+	Exists(name string) bool
+	Expired(name string, maxAge time.Duration) bool
+	// Store saves data under name, nil deletes it
+	Store(name string, data []byte) error
+	StoreJSON(name string, v interface{}) error
+	LoadJSON(name string, v interface{}) error
+	LoadOrStoreJSON(name string, maxAge time.Duration, reload func() (interface{}, error), v interface{}) error
+	// Age is how long ago name was stored
+	Age(name string) (time.Duration, error)
+}
+
+var (
+	memoryCacheOnce sync.Once
+	memoryCacheInst *memoryCache
+)
+
+// cache returns the backend picked by CACHE_BACKEND.
+func cache() cacheBackend {
+	switch config("CACHE_BACKEND") {
+	case "memory":
+		memoryCacheOnce.Do(func() {
+			memoryCacheInst = &memoryCache{entries: map[string]memoryEntry{}}
+		})
+		return memoryCacheInst
+	case "sqlite":
+		return sqliteCache{filename: filepath.Join(cacheDir(), sqliteCacheFile)}
+	}
+	return aw.NewCache(cacheDir())
+}
+
+// loadOrStoreJSON implements LoadOrStoreJSON on top of the other methods,
+// for the backends that are not awgo's.
+func loadOrStoreJSON(c cacheBackend, name string, maxAge time.Duration, reload func() (interface{}, error), v interface{}) error {
+	if c.Exists(name) && (maxAge == 0 || !c.Expired(name, maxAge)) {
+		return c.LoadJSON(name, v)
+	}
+	fresh, err := reload()
+	if err != nil {
+		return fmt.Errorf("couldn't reload data: %v", err)
+	}
+	if err := c.StoreJSON(name, fresh); err != nil {
+		return err
+	}
+	return c.LoadJSON(name, v)
+}
+
+// memoryCache only lasts as long as the process, which suits a long running
+// one. for the one-shot script filter it means no caching at all.
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	data   []byte
+	stored time.Time
+}
+
+func (c *memoryCache) Exists(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[name]
+	return ok
+}
+
+func (c *memoryCache) Expired(name string, maxAge time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[name]
+	return !ok || time.Since(e.stored) > maxAge
+}
+
+func (c *memoryCache) Age(name string) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[name]
+	if !ok {
+		return 0, os.ErrNotExist
+	}
+	return time.Since(e.stored), nil
+}
+
+func (c *memoryCache) Store(name string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if data == nil {
+		delete(c.entries, name)
+		return nil
+	}
+	c.entries[name] = memoryEntry{data, time.Now()}
+	return nil
+}
+
+func (c *memoryCache) StoreJSON(name string, v interface{}) error {
+	if v == nil {
+		return c.Store(name, nil)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal JSON: %v", err)
+	}
+	return c.Store(name, data)
+}
+
+func (c *memoryCache) LoadJSON(name string, v interface{}) error {
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	c.mu.Unlock()
+	if !ok {
+		return os.ErrNotExist
+	}
+	return json.Unmarshal(e.data, v)
+}
+
+func (c *memoryCache) LoadOrStoreJSON(name string, maxAge time.Duration, reload func() (interface{}, error), v interface{}) error {
+	return loadOrStoreJSON(c, name, maxAge, reload, v)
+}
+
+// sqliteCache keeps every entry in one table of a sqlite database, via the
+// same sqlite3 binary as the local index.
+type sqliteCache struct {
+	filename string
+}
+
+const sqliteCacheFile = "cache.sqlite"
+
+const sqliteCacheSchema = `CREATE TABLE IF NOT EXISTS cache (
+	name TEXT PRIMARY KEY,
+	data BLOB NOT NULL,
+	stored INTEGER NOT NULL
+);
+`
+
+func (c sqliteCache) exec(script string) ([][]string, error) {
+	if err := os.MkdirAll(filepath.Dir(c.filename), os.ModePerm); err != nil {
+		return nil, err
+	}
+	return sqliteExec(c.filename, sqliteCacheSchema+script)
+}
+
+// stored returns when name was stored, or the zero time if it is missing.
+func (c sqliteCache) stored(name string) time.Time {
+	rows, err := c.exec(fmt.Sprintf("SELECT stored FROM cache WHERE name = %s;\n", sqlQuote(name)))
+	if err != nil {
+		debug("failed to read cache: %s", err.Error())
+		return time.Time{}
+	}
+	if len(rows) == 0 {
+		return time.Time{}
+	}
+	n, _ := strconv.ParseInt(rows[0][0], 10, 64)
+	return time.Unix(n, 0)
+}
+
+func (c sqliteCache) Exists(name string) bool {
+	return !c.stored(name).IsZero()
+}
+
+func (c sqliteCache) Expired(name string, maxAge time.Duration) bool {
+	t := c.stored(name)
+	return t.IsZero() || time.Since(t) > maxAge
+}
+
+func (c sqliteCache) Age(name string) (time.Duration, error) {
+	t := c.stored(name)
+	if t.IsZero() {
+		return 0, os.ErrNotExist
+	}
+	return time.Since(t), nil
+}
+
+func (c sqliteCache) Store(name string, data []byte) error {
+	if data == nil {
+		_, err := c.exec(fmt.Sprintf("DELETE FROM cache WHERE name = %s;\n", sqlQuote(name)))
+		return err
+	}
+	_, err := c.exec(fmt.Sprintf(
+		"INSERT OR REPLACE INTO cache VALUES (%s, X'%s', %d);\n",
+		sqlQuote(name),
+		hex.EncodeToString(data),
+		time.Now().Unix(),
+	))
+	return err
+}
+
+func (c sqliteCache) StoreJSON(name string, v interface{}) error {
+	if v == nil {
+		return c.Store(name, nil)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal JSON: %v", err)
+	}
+	return c.Store(name, data)
+}
+
+func (c sqliteCache) LoadJSON(name string, v interface{}) error {
+	rows, err := c.exec(fmt.Sprintf("SELECT hex(data) FROM cache WHERE name = %s;\n", sqlQuote(name)))
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return os.ErrNotExist
+	}
+	data, err := hex.DecodeString(strings.TrimSpace(rows[0][0]))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c sqliteCache) LoadOrStoreJSON(name string, maxAge time.Duration, reload func() (interface{}, error), v interface{}) error {
+	return loadOrStoreJSON(c, name, maxAge, reload, v)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// charts are the itunes rss top lists for mac apps.
+var charts = map[string]struct {
+	feed  string
+	title string
+}{
+	"top-free":     {"topfreemacapps", "Top Free"},
+	"top-paid":     {"toppaidmacapps", "Top Paid"},
+	"top-grossing": {"topgrossingmacapps", "Top Grossing"},
+}
+
+var chartNames = []string{"top-free", "top-paid", "top-grossing"}
+
+const (
+	chartSize   = 100
+	chartMaxAge = time.Hour * 3
+	// cached charts older than this are too stale to annotate results with
+	chartAnnotationMaxAge = time.Hour * 48
+)
+
+type chartEntry struct {
+	Rank      int    `json:"rank"`
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Developer string `json:"developer"`
+	Price     string `json:"price"`
+	Artwork   string `json:"artwork"`
+	URL       string `json:"url"`
+	Genre     string `json:"genre"`
+}
+
+type label struct {
+	Label string `json:"label"`
+}
+
+type rssEntry struct {
+	Name   label   `json:"im:name"`
+	Artist label   `json:"im:artist"`
+	Price  label   `json:"im:price"`
+	Image  []label `json:"im:image"`
+	ID     struct {
+		Label      string `json:"label"`
+		Attributes struct {
+			ID string `json:"im:id"`
+		} `json:"attributes"`
+	} `json:"id"`
+	Category struct {
+		Attributes struct {
+			Label string `json:"label"`
+		} `json:"attributes"`
+	} `json:"category"`
+}
+
+// rssEntries exists because the feed collapses a one element entry list into
+// a bare object.
+type rssEntries []rssEntry
+
+func (e *rssEntries) UnmarshalJSON(b []byte) error {
+	var list []rssEntry
+	if err := json.Unmarshal(b, &list); err == nil {
+		*e = list
+		return nil
+	}
+	var one rssEntry
+	if err := json.Unmarshal(b, &one); err != nil {
+		return err
+	}
+	*e = rssEntries{one}
+	return nil
+}
+
+// chart returns the named chart, optionally limited to a genre (0 means all
+// genres). results are cached for chartMaxAge.
+func chart(ctx context.Context, name string, genre int) ([]chartEntry, error) {
+	c, ok := charts[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown chart %q", name)
+	}
+	key := chartCacheKey(name, genre)
+	var entries []chartEntry
+	err := cache().LoadOrStoreJSON(key, chartMaxAge, func() (interface{}, error) {
+		return fetchChart(ctx, c.feed, genre)
+	}, &entries)
+	return entries, err
+}
+
+func chartCacheKey(name string, genre int) string {
+	return fmt.Sprintf("chart-%s-%s-%d.json", config("COUNTRY"), name, genre)
+}
+
+func fetchChart(ctx context.Context, feed string, genre int) ([]chartEntry, error) {
+	u := fmt.Sprintf("https://itunes.apple.com/%s/rss/%s/limit=%d", config("COUNTRY"), feed, chartSize)
+	if genre != 0 {
+		u += fmt.Sprintf("/genre=%d", genre)
+	}
+	req, err := http.NewRequest("GET", u+"/json", http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	debug("sending request: %s %s", req.Method, req.URL.String())
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	var body struct {
+		Feed struct {
+			Entry rssEntries `json:"entry"`
+		} `json:"feed"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	entries := make([]chartEntry, 0, len(body.Feed.Entry))
+	for i, e := range body.Feed.Entry {
+		id, _ := strconv.ParseInt(e.ID.Attributes.ID, 10, 64)
+		ce := chartEntry{
+			Rank:      i + 1,
+			ID:        id,
+			Name:      e.Name.Label,
+			Developer: e.Artist.Label,
+			Price:     e.Price.Label,
+			URL:       e.ID.Label,
+			Genre:     e.Category.Attributes.Label,
+		}
+		// images come smallest first
+		if len(e.Image) > 0 {
+			ce.Artwork = e.Image[len(e.Image)-1].Label
+		}
+		entries = append(entries, ce)
+	}
+	return entries, nil
+}
+
+func init() {
+	registerRefreshJob("charts", func(ctx context.Context) error {
+		c := cache()
+		for _, name := range chartNames {
+			// expire the cached copy so chart() fetches a fresh one
+			if err := c.Store(chartCacheKey(name, 0), nil); err != nil {
+				return err
+			}
+			if _, err := chart(ctx, name, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+var (
+	chartPositionsOnce sync.Once
+	chartPositionsByID map[int64]string
+)
+
+// chartPosition describes where an app currently charts, e.g. "#3 Top Paid",
+// or "" if it does not. only charts that are already cached are consulted, a
+// search never waits on the chart feeds.
+func chartPosition(id int64) string {
+	chartPositionsOnce.Do(func() {
+		chartPositionsByID = map[int64]string{}
+		c := cache()
+		for _, name := range chartNames {
+			key := chartCacheKey(name, 0)
+			if !c.Exists(key) || c.Expired(key, chartAnnotationMaxAge) {
+				continue
+			}
+			var entries []chartEntry
+			if err := c.LoadJSON(key, &entries); err != nil {
+				debug("failed to load cached chart %s: %s", name, err.Error())
+				continue
+			}
+			for _, e := range entries {
+				// charts are checked best first, keep the first hit
+				if _, ok := chartPositionsByID[e.ID]; !ok {
+					chartPositionsByID[e.ID] = fmt.Sprintf("#%d %s", e.Rank, charts[name].title)
+				}
+			}
+		}
+	})
+	return chartPositionsByID[id]
+}
+
+// genreRank is where the app stands in the top chart of its primary genre,
+// e.g. "#12 in Productivity", or "" when it is not in the top chartSize. free
+// apps are looked for in the top free chart and paid ones in the top paid.
+func genreRank(ctx context.Context, res app) string {
+	if res.GenreID == 0 || res.Kind != "mac-software" {
+		return ""
+	}
+	name := "top-free"
+	if res.Price > 0 {
+		name = "top-paid"
+	}
+	entries, err := chart(ctx, name, res.GenreID)
+	if err != nil {
+		debug("failed to load the %s chart for %s: %s", name, res.Genre, err.Error())
+		return ""
+	}
+	for _, e := range entries {
+		if e.ID == res.ID {
+			return fmt.Sprintf("#%d in %s", e.Rank, res.Genre)
+		}
+	}
+	return ""
+}
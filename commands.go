@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	name  string
+	usage string
+	desc  string
+	run   func(ctx context.Context, args []string) error
+}
+
+var (
+	commands       = map[string]command{}
+	commandAliases = map[string]string{}
+)
+
+func register(c command) {
+	if _, ok := commands[c.name]; ok {
+		panic(fmt.Sprintf("command %q registered twice", c.name))
+	}
+	commands[c.name] = c
+}
+
+// lookupCommand resolves the subcommand named by the first argument. anything
+// that is not a known subcommand is treated as a search term, which keeps
+// older workflow installs (that call the binary with just the query) working.
+func lookupCommand(args []string) (command, []string) {
+	if len(args) > 0 {
+		name := args[0]
+		if alias, ok := commandAliases[name]; ok {
+			name = alias
+		}
+		if c, ok := commands[name]; ok {
+			return c, args[1:]
+		}
+	}
+	return commands["search"], args
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [arguments]\n\ncommands:\n", os.Args[0])
+	for _, name := range commandNames() {
+		c := commands[name]
+		fmt.Fprintf(os.Stderr, "  %-24s %s\n", c.usage, c.desc)
+	}
+}
+
+func init() {
+	register(command{
+		name:  "help",
+		usage: "help",
+		desc:  "show this message",
+		run: func(ctx context.Context, args []string) error {
+			usage()
+			return nil
+		},
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+func init() {
+	registerAction(action{
+		name: "compare-platforms",
+		desc: "Compare the mac and iOS versions",
+		arg:  func(res app) string { return strconv.FormatInt(res.ID, 10) },
+		run: func(ctx context.Context, arg string) error {
+			return searchAgain(ctx, "compare:"+arg)
+		},
+	})
+}
+
+// otherPlatformEntity is the search entity for the platform res was not
+// released for.
+func otherPlatformEntity(res app) string {
+	if res.Kind == "mac-software" {
+		return platforms["ios"]
+	}
+	return platforms["mac"]
+}
+
+// samePlatformTitle reports whether a and b look like one app released for
+// two platforms: the same developer, and either the same name or bundle ids
+// that only differ in their last part (com.example.notes and
+// com.example.notes-ios).
+func samePlatformTitle(a, b app) bool {
+	if !strings.EqualFold(a.Developer, b.Developer) {
+		return false
+	}
+	if normalizeQuery(a.Name) == normalizeQuery(b.Name) {
+		return true
+	}
+	family := func(bundleID string) string {
+		if i := strings.LastIndexByte(bundleID, '.'); i > 0 {
+			return strings.ToLower(bundleID[:i])
+		}
+		return strings.ToLower(bundleID)
+	}
+	if a.BundleID == "" || b.BundleID == "" || family(a.BundleID) != family(b.BundleID) {
+		return false
+	}
+	an, bn := normalizeQuery(a.Name), normalizeQuery(b.Name)
+	return strings.HasPrefix(an, bn) || strings.HasPrefix(bn, an)
+}
+
+// counterparts finds the releases of res on the other platform.
+func counterparts(ctx context.Context, res app) ([]app, error) {
+	results, err := searchBackend(ctx, config("COUNTRY"), otherPlatformEntity(res), res.Name, "", 25)
+	if err != nil {
+		return nil, err
+	}
+	var found []app
+	for _, other := range results {
+		if other.ID == res.ID || samePlatformTitle(res, other) {
+			found = append(found, other)
+		}
+	}
+	return found, nil
+}
+
+// compareFeedback lists the app with track id id next to its releases on
+// the other platform, leading with the price of each.
+func compareFeedback(ctx context.Context, fb *aw.Feedback, id int64) error {
+	res, err := lookup(ctx, id)
+	if isRateLimit(err) {
+		fb.Items = append(fb.Items, limitItem(err, false))
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if res == nil {
+		fb.NewItem(fmt.Sprintf("no app found with id %d", id)).
+			Icon(aw.IconWarning).
+			Valid(false)
+		return nil
+	}
+	others, err := counterparts(ctx, *res)
+	if isRateLimit(err) {
+		fb.Items = append(fb.Items, limitItem(err, false))
+	} else if err != nil {
+		return err
+	}
+	listings := append([]app{*res}, others...)
+	images := make([]string, len(listings))
+	for i, l := range listings {
+		images[i] = l.Artwork
+	}
+	icons := downloadAllImages(ctx, downloadConcurrency(), images)
+	for i, l := range listings {
+		price := l.PriceFmt
+		if l.ID == res.ID && l.Kind != res.Kind {
+			price = "universal purchase, included"
+		}
+		fb.Items = append(fb.Items, appItem(l).
+			Title(platformLabel(l)+" · "+price+" · "+l.Name).
+			Icon(icons[i]))
+	}
+	if len(others) == 0 && err == nil {
+		fb.NewItem("Only on " + platformLabel(*res)).
+			Subtitle("no release by " + res.Developer + " found for the other platform").
+			Icon(aw.IconInfo).
+			Valid(false)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	register(command{
+		name:  "completion",
+		usage: "completion bash|zsh|fish",
+		desc:  "print a shell completion script",
+		run:   completionCmd,
+	})
+}
+
+// storefrontCodes are the countries with an app store.
+var storefrontCodes = strings.Fields(`
+	ae ag ai al am ao ar at au az bb be bf bg bh bj bm bn bo br bs bt bw by bz
+	ca cg ch cl cn co cr cv cy cz de dk dm do dz ec ee eg es fi fj fm fr gb gd
+	gh gm gr gt gw gy hk hn hr hu id ie il in is it jm jo jp ke kg kh kn kr kw
+	ky kz la lb lc lk lr lt lu lv md mg mk ml mn mo mr ms mt mu mw mx my mz na
+	ne ng ni nl no np nz om pa pe pg ph pk pl pt pw py qa ro ru sa sb sc se sg
+	si sk sl sn sr st sv sz tc td th tj tm tn tr tt tw tz ua ug us uy uz vc ve
+	vg vn ye za zw`)
+
+var (
+	usageVerbs = regexp.MustCompile(`^[a-z-]+(\|[a-z-]+(\s<[^>]+>)*)+`)
+	usageFlags = regexp.MustCompile(`\[(-[a-z-]+)`)
+)
+
+// commandWords are what can follow the command name, read off its usage:
+// the sub-commands of "x list|add|remove", the flags of "x [-n count]".
+func commandWords(c command) []string {
+	rest := strings.TrimSpace(strings.TrimPrefix(c.usage, c.name))
+	var words []string
+	if verbs := usageVerbs.FindString(rest); verbs != "" {
+		for _, v := range strings.Split(verbs, "|") {
+			words = append(words, strings.Fields(v)[0])
+		}
+	}
+	for _, m := range usageFlags.FindAllStringSubmatch(rest, -1) {
+		words = append(words, m[1])
+	}
+	return words
+}
+
+func completionCmd(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: completion bash|zsh|fish")
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		fmt.Print(zshCompletion())
+	case "fish":
+		fmt.Print(fishCompletion())
+	default:
+		return fmt.Errorf("unknown shell %q, expected bash, zsh or fish", args[0])
+	}
+	return nil
+}
+
+// eval "$(alfred-apple-app-search completion bash)"
+func bashCompletion() string {
+	fn := "_" + strings.Replace(binaryName, "-", "_", -1)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=${COMP_WORDS[COMP_CWORD]}\n")
+	b.WriteString("\tif [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	b.WriteString("\t\treturn\n\tfi\n")
+	b.WriteString("\tif [[ $cur == country=* ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -P country= -W %q -- \"${cur#country=}\"))\n", strings.Join(storefrontCodes, " "))
+	b.WriteString("\t\treturn\n\tfi\n")
+	b.WriteString("\tcase ${COMP_WORDS[1]} in\n")
+	for _, name := range commandNames() {
+		if words := commandWords(commands[name]); len(words) > 0 {
+			fmt.Fprintf(&b, "\t%s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", name, strings.Join(words, " "))
+		}
+	}
+	b.WriteString("\tesac\n}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, binaryName)
+	return b.String()
+}
+
+// alfred-apple-app-search completion zsh > "${fpath[1]}/_alfred-apple-app-search"
+func zshCompletion() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n\n", binaryName)
+	b.WriteString("if (( CURRENT == 2 )); then\n\tlocal -a cmds\n\tcmds=(\n")
+	for _, name := range commandNames() {
+		fmt.Fprintf(&b, "\t\t%q\n", name+":"+commands[name].desc)
+	}
+	b.WriteString("\t)\n\t_describe command cmds\n\treturn\nfi\n")
+	b.WriteString("if [[ $PREFIX == country=* ]]; then\n\tcompset -P 'country='\n")
+	fmt.Fprintf(&b, "\tcompadd -- %s\n\treturn\nfi\n", strings.Join(storefrontCodes, " "))
+	b.WriteString("case $words[2] in\n")
+	for _, name := range commandNames() {
+		if words := commandWords(commands[name]); len(words) > 0 {
+			fmt.Fprintf(&b, "%s) compadd -- %s ;;\n", name, strings.Join(words, " "))
+		}
+	}
+	b.WriteString("esac\n")
+	return b.String()
+}
+
+// alfred-apple-app-search completion fish > ~/.config/fish/completions/alfred-apple-app-search.fish
+func fishCompletion() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "complete -c %s -f\n", binaryName)
+	for _, name := range commandNames() {
+		c := commands[name]
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n", binaryName, name, fishQuote(c.desc))
+		for _, w := range commandWords(c) {
+			if strings.HasPrefix(w, "-") {
+				fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from %s' -o %s\n", binaryName, name, w[1:])
+			} else {
+				fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from %s' -a %s\n", binaryName, name, w)
+			}
+		}
+	}
+	countries := make([]string, len(storefrontCodes))
+	for i, c := range storefrontCodes {
+		countries[i] = "country=" + c
+	}
+	fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from trigger' -a '%s'\n", binaryName, strings.Join(countries, " "))
+	return b.String()
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
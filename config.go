@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// setting is a workflow variable the user can change. alfred 5 shows these in
+// the workflow's user configuration sheet (see package.go) and injects the
+// values as environment variables, older alfred versions just use the
+// variables directly.
+type setting struct {
+	key   string
+	label string
+	desc  string
+	def   string
+	check func(v string) error
+}
+
+var settings = []setting{
+	{
+		key:   "COUNTRY",
+		label: "Country",
+		desc:  "two letter code of the app store storefront to search, e.g. us, gb, de",
+		def:   "us",
+		check: checkCountry,
+	},
+	{
+		key:   "PROVIDERS",
+		label: "Providers",
+		desc:  "comma separated catalogs to search, the first is used unless a query says from:name",
+		def:   "appstore",
+		check: checkProviders,
+	},
+	{
+		key:   "BACKEND",
+		label: "Backend",
+		desc:  "itunes uses the public search api, amp the api apps.apple.com uses, which adds editorial notes and in-app purchases and falls back to itunes when it fails",
+		def:   "itunes",
+		check: checkOneOf("itunes", "amp"),
+	},
+	{
+		key:   "EXTRA_COUNTRIES",
+		label: "Extra countries",
+		desc:  "comma separated storefronts to search alongside Country, e.g. gb,de. results note the countries they were found in",
+		def:   "",
+		check: checkCountries,
+	},
+	{
+		key:   "PLATFORMS",
+		label: "Platforms",
+		desc:  "comma separated platforms to search: mac, ios. more than one labels each result with its platform",
+		def:   "mac",
+		check: checkList(platformNames),
+	},
+	{
+		key:   "STRIP_DIACRITICS",
+		label: "Strip diacritics",
+		desc:  "yes searches for cafe when you type café",
+		def:   "no",
+		check: checkOneOf("yes", "no"),
+	},
+	{
+		key:   "HOME_CURRENCY",
+		label: "Home currency",
+		desc:  "three letter currency code, e.g. USD. prices in other currencies also show roughly what they come to in it",
+		def:   "",
+		check: checkCurrency,
+	},
+	{
+		key:   "RESULT_LIMIT",
+		label: "Result limit",
+		desc:  "how many results to ask the app store for (1-200)",
+		def:   "20",
+		check: checkIntBetween(1, 200),
+	},
+	{
+		key:   "RESULTS_HEADER",
+		label: "Results header",
+		desc:  "yes starts results with a summary of the search (count, store, cache age), ↩ on it searches again skipping the cache",
+		def:   "no",
+		check: checkOneOf("yes", "no"),
+	},
+	{
+		key:   "PRIMARY_ACTION",
+		label: "Primary action",
+		desc:  "what ↩ opens: app (the mac app store) or browser (apps.apple.com). ⌥↩ opens the other",
+		def:   "app",
+		check: checkOneOf("app", "browser"),
+	},
+	{
+		key:   "MOD_CMD",
+		label: "⌘ action",
+		desc:  "action for ⌘↩, see the readme for the list. none disables it",
+		def:   "search-reviews",
+		check: checkAction,
+	},
+	{
+		key:   "MOD_ALT",
+		label: "⌥ action",
+		desc:  "action for ⌥↩, see the readme for the list. none disables it",
+		def:   "open-other",
+		check: checkAction,
+	},
+	{
+		key:   "MOD_CTRL",
+		label: "⌃ action",
+		desc:  "action for ⌃↩, see the readme for the list. none disables it",
+		def:   "wishlist-add",
+		check: checkAction,
+	},
+	{
+		key:   "MOD_SHIFT",
+		label: "⇧ action",
+		desc:  "action for ⇧↩, see the readme for the list. none disables it",
+		def:   "open-alternative",
+		check: checkAction,
+	},
+	{
+		key:   "MOD_FN",
+		label: "fn action",
+		desc:  "action for fn↩, see the readme for the list. none disables it",
+		def:   "none",
+		check: checkAction,
+	},
+	{
+		key:   "REMINDER_APP",
+		label: "Reminder app",
+		desc:  "where the remind action files its to-do: reminders or things",
+		def:   "reminders",
+		check: checkOneOf("reminders", "things"),
+	},
+	{
+		key:   "SHARE_VIA",
+		label: "Share via",
+		desc:  "what the share action writes the message in: mail or messages",
+		def:   "mail",
+		check: checkOneOf("mail", "messages"),
+	},
+	{
+		key:   "SUBTITLE_FIELDS",
+		label: "Subtitle fields",
+		desc:  "comma separated fields to show under each result: " + strings.Join(subtitleFieldNames, ", "),
+		def:   "developer,price,rating,age,updated,gamecenter,chart,foss",
+		check: checkList(subtitleFieldNames),
+	},
+	{
+		key:   "RATING_GLYPH",
+		label: "Rating glyph",
+		desc:  "character repeated for each star of a rating, e.g. ★ or ⭐️. none leaves stars out",
+		def:   string(star),
+	},
+	{
+		key:   "RATING_COUNT",
+		label: "Rating count",
+		desc:  "compact shortens large rating counts to 12.3k or 1.2M, exact shows every digit",
+		def:   "compact",
+		check: checkOneOf("compact", "exact"),
+	},
+	{
+		key:   "SUBTITLE_STYLE",
+		label: "Subtitle style",
+		desc:  "compact uses glyphs and | separators, spoken uses plain words that voiceover reads sensibly",
+		def:   "compact",
+		check: checkOneOf("compact", "spoken"),
+	},
+	{
+		key:   "ARTWORK",
+		label: "Artwork",
+		desc:  "download app icons, or none to use the workflow icon for every result (fastest, no image traffic)",
+		def:   "download",
+		check: checkOneOf("download", "none"),
+	},
+	{
+		key:   "ICON_SIZE",
+		label: "Icon size",
+		desc:  "largest width/height in pixels icons are stored at, 0 keeps them as downloaded",
+		def:   "256",
+		check: checkIntBetween(0, 1024),
+	},
+	{
+		key:   "ICON_BADGES",
+		label: "Icon badges",
+		desc:  "comma separated badges drawn on icons: update, installed, paid, or none",
+		def:   "update,installed",
+		check: checkBadges,
+	},
+	{
+		key:   "API_RATE_LIMIT",
+		label: "API rate limit",
+		desc:  "most itunes api calls to make per minute, across searches and background jobs, 0 for no limit",
+		def:   "20",
+		check: checkIntBetween(0, 600),
+	},
+	{
+		key:   "DOWNLOAD_CONCURRENCY",
+		label: "Download concurrency",
+		desc:  "how many icons to download at once",
+		def:   "12",
+		check: checkIntBetween(1, 64),
+	},
+	{
+		key:   "MIN_RATINGS",
+		label: "Minimum ratings",
+		desc:  "hide apps with fewer ratings than this",
+		def:   "0",
+		check: checkIntBetween(0, 1<<31-1),
+	},
+	{
+		key:   "MAX_AGE_RATING",
+		label: "Maximum age rating",
+		desc:  "hide apps rated above this age, e.g. 12+. empty shows everything",
+		def:   "",
+		check: checkAgeRating,
+	},
+	{
+		key:   "STALE_AFTER_DAYS",
+		label: "Stale after (days)",
+		desc:  "flag apps not updated in this many days, 0 disables",
+		def:   "730",
+		check: checkIntBetween(0, 1<<31-1),
+	},
+	{
+		key:   "EXPORT_TARGET",
+		label: "Export target",
+		desc:  "where the export-json action puts the metadata: file or clipboard",
+		def:   "file",
+		check: checkOneOf("file", "clipboard"),
+	},
+	{
+		key:   "EXPORT_DIR",
+		label: "Export folder",
+		desc:  "folder export-json writes files to, empty means ~/Downloads",
+		def:   "",
+	},
+	{
+		key:   "DIGEST_DIR",
+		label: "Digest folder",
+		desc:  "folder the weekly wishlist digest is written to, empty means a digests folder in the workflow data dir",
+		def:   "",
+	},
+	{
+		key:   "FEED_FILE",
+		label: "Changelog feed",
+		desc:  "file the atom feed of new versions of wishlist apps is written to, empty means changelog.atom in the workflow data dir",
+		def:   "",
+	},
+	{
+		key:   "FOSS_ALTERNATIVES_URL",
+		label: "FOSS alternatives list",
+		desc:  "url of a json object mapping app names to {name, url} of an open source alternative, added to the built in list",
+		def:   "",
+	},
+	{
+		key:   "CACHE_BACKEND",
+		label: "Cache backend",
+		desc:  "where results, charts and catalogs are cached: file, sqlite, or memory (only useful to a long running process)",
+		def:   "file",
+		check: checkOneOf("file", "sqlite", "memory"),
+	},
+	{
+		key:   "PRIVATE",
+		label: "Private mode",
+		desc:  "1 keeps searches and picked apps off disk: no search history, recently viewed apps, result cache or local index",
+		def:   "0",
+		check: checkOneOf("0", "1"),
+	},
+	{
+		key:   "CRASH_REPORT_DSN",
+		label: "Crash report DSN",
+		desc:  "sentry compatible dsn crashes and failed commands are reported to, with the workflow and macos versions but never what was searched for. empty sends nothing",
+		def:   "",
+		check: checkDSN,
+	},
+	{
+		key:   "HOOK_SCRIPT",
+		label: "Hook script",
+		desc:  "executable run after every action, with the app's details in its environment",
+		def:   "",
+	},
+	{
+		key:   "NOTIFY",
+		label: "Notifications",
+		desc:  "comma separated events to notify about: " + strings.Join(notifyEvents, ", ") + ". none turns them all off",
+		def:   strings.Join(notifyEvents, ","),
+		check: checkList(append([]string{"none"}, notifyEvents...)),
+	},
+	{
+		key:   "CA_BUNDLE",
+		label: "CA bundle",
+		desc:  "pem file of extra certificates to trust, for networks with a tls intercepting proxy",
+		def:   "",
+		check: checkCABundle,
+	},
+	{
+		key:   "CONNECT_ISSUER_ID",
+		label: "App Store Connect issuer id",
+		desc:  "issuer id of an app store connect api key, for the mine: operator",
+		def:   "",
+	},
+	{
+		key:   "CONNECT_KEY_ID",
+		label: "App Store Connect key id",
+		desc:  "id of the app store connect api key",
+		def:   "",
+	},
+	{
+		key:   "CONNECT_KEY_FILE",
+		label: "App Store Connect key file",
+		desc:  "path of the .p8 file downloaded for the app store connect api key",
+		def:   "",
+		check: checkConnectKey,
+	},
+	{
+		key:   "TRACE",
+		label: "Trace requests",
+		desc:  "1 logs every http request and response (headers, timings, the start of the body) to trace.log in the workflow cache dir",
+		def:   "0",
+		check: checkOneOf("0", "1"),
+	},
+	{
+		key:   "VPP_DETAIL",
+		label: "Volume purchase in details",
+		desc:  "yes shows in the detail view whether an app can be bought in volume through apple business manager, at the cost of another request",
+		def:   "no",
+		check: checkOneOf("yes", "no"),
+	},
+	{
+		key:   "SHOW_TIMING",
+		label: "Show timing",
+		desc:  "yes adds how long the workflow took to the first item's subtitle",
+		def:   "no",
+		check: checkOneOf("yes", "no"),
+	},
+	{
+		key:   "USER_AGENT",
+		label: "User agent",
+		desc:  "user agent sent with every request, empty uses the default",
+		def:   "",
+	},
+}
+
+func lookupSetting(key string) setting {
+	if s := lookupSettingOK(key); s != nil {
+		return *s
+	}
+	panic(fmt.Sprintf("unknown setting %q", key))
+}
+
+func lookupSettingOK(key string) *setting {
+	for i := range settings {
+		if settings[i].key == key {
+			return &settings[i]
+		}
+	}
+	return nil
+}
+
+// config returns the value of the setting key, falling back to its default
+// when it is unset or invalid. invalid values are reported by configErrors.
+// credentials left empty are looked for in the keychain, see secret.
+func config(key string) string {
+	s := lookupSetting(key)
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" && isSecret(key) {
+		v = secret(key)
+	}
+	if v == "" {
+		return s.def
+	}
+	if s.check != nil && s.check(v) != nil {
+		return s.def
+	}
+	return v
+}
+
+func configInt(key string) int {
+	n, err := strconv.Atoi(config(key))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// configErrors validates every setting that has been given a value.
+func configErrors() []error {
+	var errs []error
+	for _, s := range settings {
+		v := strings.TrimSpace(os.Getenv(s.key))
+		if v == "" || s.check == nil {
+			continue
+		}
+		if err := s.check(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s, using %q", s.label, err.Error(), s.def))
+		}
+	}
+	return errs
+}
+
+func checkCountry(v string) error {
+	if len(v) != 2 || strings.ToLower(v) != strings.Map(func(r rune) rune {
+		if r < 'a' || r > 'z' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(v)) {
+		return fmt.Errorf("%q is not a two letter country code", v)
+	}
+	return nil
+}
+
+func checkCountries(v string) error {
+	for _, c := range strings.Split(v, ",") {
+		if err := checkCountry(strings.TrimSpace(c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkBadges(v string) error {
+	if v == "none" {
+		return nil
+	}
+	return checkList(badgeNames)(v)
+}
+
+func checkIntBetween(min, max int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min || n > max {
+			return fmt.Errorf("%q is not a number between %d and %d", v, min, max)
+		}
+		return nil
+	}
+}
+
+func checkOneOf(allowed ...string) func(string) error {
+	return func(v string) error {
+		for _, a := range allowed {
+			if v == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", v, strings.Join(allowed, ", "))
+	}
+}
+
+func checkList(allowed []string) func(string) error {
+	return func(v string) error {
+	fields:
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			for _, a := range allowed {
+				if f == a {
+					continue fields
+				}
+			}
+			return fmt.Errorf("unknown field %q", f)
+		}
+		return nil
+	}
+}
+
+func checkAgeRating(v string) error {
+	if ageRating(v) == 0 {
+		return fmt.Errorf("%q is not an age rating like 12+", v)
+	}
+	return nil
+}
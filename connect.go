@@ -0,0 +1,424 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+// app store connect is apple's api for developers, it needs a token signed
+// with an api key made in app store connect under users and access.
+const (
+	connectBaseURL  = "https://api.appstoreconnect.apple.com/v1/"
+	connectAppsFile = "connect-apps.json"
+	connectAppsTTL  = time.Hour
+	// apple refuses tokens that live longer than 20 minutes
+	connectTokenTTL = time.Minute * 20
+)
+
+// connectConfigured reports whether an api key has been set up, in workflow
+// variables or the keychain.
+func connectConfigured() bool {
+	return config("CONNECT_ISSUER_ID") != "" && config("CONNECT_KEY_ID") != "" && (config("CONNECT_KEY_FILE") != "" || secret("CONNECT_KEY") != "")
+}
+
+func connectKey(filename string) (*ecdsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a .p8 key file", filename)
+	}
+	return parseConnectKey(block.Bytes)
+}
+
+func parseConnectKey(der []byte) (*ecdsa.PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	ec, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an app store connect api key")
+	}
+	return ec, nil
+}
+
+// connectSigningKey is the key from CONNECT_KEY_FILE, or failing that the one
+// stored in the keychain with secret set CONNECT_KEY.
+func connectSigningKey() (*ecdsa.PrivateKey, error) {
+	if filename := config("CONNECT_KEY_FILE"); filename != "" {
+		return connectKey(filename)
+	}
+	der, err := base64.StdEncoding.DecodeString(secret("CONNECT_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("the key in the keychain is damaged, store it again: %s", err.Error())
+	}
+	return parseConnectKey(der)
+}
+
+// connectToken makes the es256 signed jwt the api wants as a bearer token.
+func connectToken() (string, error) {
+	key, err := connectSigningKey()
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	header, err := json.Marshal(map[string]string{
+		"alg": "ES256",
+		"kid": config("CONNECT_KEY_ID"),
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": config("CONNECT_ISSUER_ID"),
+		"iat": now.Unix(),
+		"exp": now.Add(connectTokenTTL).Unix(),
+		"aud": "appstoreconnect-v1",
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, sum[:])
+	if err != nil {
+		return "", err
+	}
+	// jws wants r and s as fixed size big endian numbers, not asn.1
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// connectResource is a json:api resource, which is all the api speaks.
+type connectResource struct {
+	Type          string          `json:"type"`
+	ID            string          `json:"id"`
+	Attributes    json.RawMessage `json:"attributes"`
+	Relationships map[string]struct {
+		Data json.RawMessage `json:"data"`
+	} `json:"relationships"`
+}
+
+// related is the id of the single resource rel points at.
+func (r connectResource) related(rel string) string {
+	var ref struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(r.Relationships[rel].Data, &ref)
+	return ref.ID
+}
+
+type connectDocument struct {
+	Data     []connectResource `json:"data"`
+	Included []connectResource `json:"included"`
+}
+
+// find returns the included resource of type typ with id.
+func (d connectDocument) find(typ, id string) (connectResource, bool) {
+	for _, r := range d.Included {
+		if r.Type == typ && r.ID == id {
+			return r, true
+		}
+	}
+	return connectResource{}, false
+}
+
+// connect sends an authenticated get to path under the api and decodes the
+// response into a document.
+func connect(ctx context.Context, path string, params url.Values) (connectDocument, error) {
+	defer timed("api")()
+	var doc connectDocument
+	token, err := connectToken()
+	if err != nil {
+		return doc, fmt.Errorf("failed to sign app store connect token: %s", err.Error())
+	}
+	req, err := http.NewRequest("GET", connectBaseURL+path+"?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return doc, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	debug("sending request: %s %s", req.Method, req.URL.String())
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return doc, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var failed struct {
+			Errors []struct {
+				Detail string `json:"detail"`
+			} `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&failed) == nil && len(failed.Errors) > 0 {
+			return doc, fmt.Errorf("app store connect: %s (%d)", failed.Errors[0].Detail, resp.StatusCode)
+		}
+		return doc, fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	return doc, json.NewDecoder(resp.Body).Decode(&doc)
+}
+
+type connectApp struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	BundleID string `json:"bundleId"`
+	SKU      string `json:"sku"`
+}
+
+// connectApps lists every app the api key can see.
+func connectApps(ctx context.Context) ([]connectApp, error) {
+	var apps []connectApp
+	err := cache().LoadOrStoreJSON(connectAppsFile, connectAppsTTL, func() (interface{}, error) {
+		doc, err := connect(ctx, "apps", url.Values{
+			"fields[apps]": {"name,bundleId,sku"},
+			"limit":        {"200"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		var apps []connectApp
+		for _, r := range doc.Data {
+			a := connectApp{ID: r.ID}
+			if err := json.Unmarshal(r.Attributes, &a); err != nil {
+				return nil, err
+			}
+			apps = append(apps, a)
+		}
+		sort.Slice(apps, func(i, j int) bool {
+			return strings.ToLower(apps[i].Name) < strings.ToLower(apps[j].Name)
+		})
+		return apps, nil
+	}, &apps)
+	return apps, err
+}
+
+func connectURL(id string) string {
+	return "https://appstoreconnect.apple.com/apps/" + id + "/appstore"
+}
+
+// mineFeedback answers the mine: operator. with no app id it lists the
+// developer's apps matching filter, with one it shows how the app is doing,
+// and mine:<id> prices lists what it costs in each territory.
+func mineFeedback(ctx context.Context, fb *aw.Feedback, id string, rest []string) {
+	if !connectConfigured() {
+		fb.NewItem("App Store Connect is not set up").
+			Subtitle("set CONNECT_ISSUER_ID, CONNECT_KEY_ID and CONNECT_KEY_FILE, or store them with the secret command").
+			Icon(aw.IconInfo).
+			Valid(false)
+		return
+	}
+	var err error
+	switch {
+	case id == "":
+		err = connectAppsFeedback(ctx, fb, strings.Join(rest, " "))
+	case len(rest) > 0 && rest[0] == "prices":
+		err = connectPricesFeedback(ctx, fb, id)
+	default:
+		err = connectAppFeedback(ctx, fb, id)
+	}
+	if err != nil {
+		fb.Items = append(fb.Items, withReportIssue(new(aw.Item).
+			Title("could not load from App Store Connect").
+			Subtitle(err.Error()).
+			Icon(aw.IconWarning).
+			Valid(false), "could not load from app store connect: "+err.Error()))
+	}
+}
+
+func connectAppsFeedback(ctx context.Context, fb *aw.Feedback, filter string) error {
+	apps, err := connectApps(ctx)
+	if err != nil {
+		return err
+	}
+	filter = strings.ToLower(filter)
+	for _, a := range apps {
+		if !strings.Contains(strings.ToLower(a.Name+" "+a.BundleID), filter) {
+			continue
+		}
+		fb.NewItem(a.Name).
+			Subtitle(a.BundleID + " · ⇥ for ratings, versions and prices").
+			Arg(connectURL(a.ID)).
+			Autocomplete("mine:" + a.ID + " ").
+			Valid(true)
+	}
+	if len(apps) == 0 {
+		fb.NewItem("no apps found").
+			Subtitle("the api key cannot see any apps").
+			Icon(aw.IconInfo).
+			Valid(false)
+	}
+	return nil
+}
+
+func connectAppFeedback(ctx context.Context, fb *aw.Feedback, id string) error {
+	trackID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not an app id", id)
+	}
+	versions, err := connect(ctx, "apps/"+id+"/appStoreVersions", url.Values{
+		"fields[appStoreVersions]": {"versionString,platform,appStoreState,createdDate"},
+		"limit":                    {"5"},
+	})
+	if err != nil {
+		return err
+	}
+	// the public lookup has the rating customers see, the api does not
+	if res, err := lookup(ctx, trackID); err == nil && res != nil {
+		fb.Items = append(fb.Items, appItem(*res).Icon(downloadAllImages(ctx, downloadConcurrency(), []string{res.Artwork})[0]))
+		fb.NewItem(strings.TrimSpace(fmt.Sprintf("%.1f %s", res.Rating, ratingGlyph()))).
+			Subtitle(fmt.Sprintf("current rating, from %s ratings", ratingCount(res.NumRatings))).
+			Icon(aw.IconInfo).
+			Valid(false)
+	} else {
+		fb.NewItem("not on the store").
+			Subtitle("the app has no live version in " + strings.ToUpper(config("COUNTRY"))).
+			Icon(aw.IconInfo).
+			Valid(false)
+	}
+	for _, v := range versions.Data {
+		var attrs struct {
+			Version  string    `json:"versionString"`
+			Platform string    `json:"platform"`
+			State    string    `json:"appStoreState"`
+			Created  time.Time `json:"createdDate"`
+		}
+		if err := json.Unmarshal(v.Attributes, &attrs); err != nil {
+			return err
+		}
+		fb.NewItem(fmt.Sprintf("%s: %s", attrs.Version, connectState(attrs.State))).
+			Subtitle(fmt.Sprintf("%s version, created %s", strings.ToLower(strings.Replace(attrs.Platform, "_", " ", -1)), relativeTime(attrs.Created))).
+			Arg(connectURL(id)).
+			Icon(aw.IconInfo).
+			Valid(true)
+	}
+	fb.NewItem("Territory pricing").
+		Subtitle("⇥ to see what the app costs in each territory").
+		Autocomplete("mine:" + id + " prices").
+		Icon(aw.IconInfo).
+		Valid(false)
+	return nil
+}
+
+// connectState turns READY_FOR_SALE into "ready for sale".
+func connectState(s string) string {
+	return strings.ToLower(strings.Replace(s, "_", " ", -1))
+}
+
+// connectPricesFeedback lists the app's price in every territory: the ones
+// set by hand, and what apple's equalization makes of the base price in the
+// rest.
+func connectPricesFeedback(ctx context.Context, fb *aw.Feedback, id string) error {
+	manual, err := connect(ctx, "appPriceSchedules/"+id+"/manualPrices", url.Values{
+		"include":                {"appPricePoint,territory"},
+		"fields[appPricePoints]": {"customerPrice,proceeds"},
+		"fields[territories]":    {"currency"},
+		"limit":                  {"200"},
+	})
+	if err != nil {
+		return err
+	}
+	type price struct {
+		territory, currency, customer, proceeds string
+		manual                                  bool
+	}
+	var prices []price
+	seen := map[string]bool{}
+	add := func(doc connectDocument, point connectResource, manual bool) {
+		var attrs struct {
+			Customer string `json:"customerPrice"`
+			Proceeds string `json:"proceeds"`
+		}
+		json.Unmarshal(point.Attributes, &attrs)
+		territory := point.related("territory")
+		if territory == "" || seen[territory] {
+			return
+		}
+		seen[territory] = true
+		var t struct {
+			Currency string `json:"currency"`
+		}
+		if r, ok := doc.find("territories", territory); ok {
+			json.Unmarshal(r.Attributes, &t)
+		}
+		prices = append(prices, price{territory, t.Currency, attrs.Customer, attrs.Proceeds, manual})
+	}
+	var base string
+	for _, mp := range manual.Data {
+		point, ok := manual.find("appPricePoints", mp.related("appPricePoint"))
+		if !ok {
+			continue
+		}
+		if point.related("territory") == "" {
+			// the point does not say, the manual price does
+			point.Relationships = mp.Relationships
+		}
+		add(manual, point, true)
+		if base == "" {
+			base = point.ID
+		}
+	}
+	if base != "" {
+		equal, err := connect(ctx, "appPricePoints/"+base+"/equalizations", url.Values{
+			"include":                {"territory"},
+			"fields[appPricePoints]": {"customerPrice,proceeds,territory"},
+			"fields[territories]":    {"currency"},
+			"limit":                  {"200"},
+		})
+		if err != nil {
+			return err
+		}
+		for _, point := range equal.Data {
+			add(equal, point, false)
+		}
+	}
+	if len(prices) == 0 {
+		fb.NewItem("no prices set").
+			Subtitle("the app has no price schedule yet").
+			Icon(aw.IconInfo).
+			Valid(false)
+		return nil
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].territory < prices[j].territory })
+	for _, p := range prices {
+		sub := "you get " + p.proceeds + " " + p.currency
+		if p.manual {
+			sub += " · set by hand"
+		}
+		fb.NewItem(fmt.Sprintf("%s  %s %s", p.territory, p.customer, p.currency)).
+			Subtitle(sub).
+			Copytext(p.customer).
+			Icon(aw.IconInfo).
+			Valid(false)
+	}
+	return nil
+}
+
+func checkConnectKey(v string) error {
+	if v == "" {
+		return nil
+	}
+	_, err := connectKey(v)
+	return err
+}
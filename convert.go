@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// alfred only reliably renders png, jpeg and gif, but some of apple's cdns
+// hand out webp or heic when they think the client can take it. those are
+// converted to png with sips, which decodes both.
+
+// artworkFormat names the image format of data: a mime type, trusting the
+// content type header only when sniffing cannot tell.
+func artworkFormat(contentType string, data []byte) string {
+	// http.DetectContentType knows webp but not heic/avif, which are iso
+	// media files with the brand at offset 8
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+		switch string(data[8:12]) {
+		case "heic", "heix", "hevc", "heim", "heis", "mif1", "msf1":
+			return "image/heic"
+		case "avif", "avis":
+			return "image/avif"
+		}
+	}
+	if mt, _, _ := mime.ParseMediaType(http.DetectContentType(data)); mt != "application/octet-stream" {
+		return mt
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	return mt
+}
+
+// renderableArtwork returns data as something alfred can draw, converting
+// it to png when it is not.
+func renderableArtwork(ctx context.Context, data []byte, contentType string) ([]byte, string, error) {
+	format := artworkFormat(contentType, data)
+	switch format {
+	case "image/png", "image/jpeg", "image/gif":
+		return data, format, nil
+	}
+	debug("converting %s artwork to png", format)
+	png, err := sipsToPNG(ctx, data)
+	if err != nil {
+		return nil, "", err
+	}
+	return png, "image/png", nil
+}
+
+func sipsToPNG(ctx context.Context, data []byte) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "artwork-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out.png")
+	if err := ioutil.WriteFile(in, data, 0600); err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sips", "-s", "format", "png", in, "--out", out)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		debug("sips: %s", stderr.String())
+		return nil, err
+	}
+	return ioutil.ReadFile(out)
+}
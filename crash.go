@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const (
+	crashFile = "crash.log"
+
+	// how long after a crash the next searches keep pointing at the log
+	crashNoticeWindow = time.Hour * 24
+)
+
+func init() {
+	registerMagic(magicAction{
+		keyword: "crash",
+		desc:    "show the last crash log",
+		items: func() []*aw.Item {
+			if it := crashItem(0); it != nil {
+				return []*aw.Item{it}
+			}
+			return []*aw.Item{
+				new(aw.Item).Title("no crashes recorded").Valid(false),
+			}
+		},
+	})
+}
+
+// writeCrash records a recovered panic along with the stack that produced it.
+// must be called from the deferred recover so the stack is still intact.
+func writeCrash(r interface{}) (string, error) {
+	stack := make([]byte, 64<<10)
+	stack = stack[:runtime.Stack(stack, false)]
+	filename, err := dataFile(crashFile)
+	if err != nil {
+		return "", err
+	}
+	// the arguments hold the search text
+	args := fmt.Sprintf("%q", os.Args[1:])
+	if private() {
+		args = "(not recorded, PRIVATE is on)"
+	}
+	report := fmt.Sprintf(
+		"time: %s\nversion: %s\nargs: %s\n\npanic: %+v\n\n%s",
+		time.Now().Format(time.RFC3339),
+		versionString(),
+		args,
+		r,
+		stack,
+	)
+	return filename, ioutil.WriteFile(filename, []byte(report), 0600)
+}
+
+// crashItem returns an item pointing at the crash log if there is one that is
+// younger than maxAge (zero means any age).
+func crashItem(maxAge time.Duration) *aw.Item {
+	filename, err := dataFile(crashFile)
+	if err != nil {
+		return nil
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil
+	}
+	if maxAge > 0 && time.Since(info.ModTime()) > maxAge {
+		return nil
+	}
+	u := &url.URL{Scheme: "file", Path: filename}
+	it := new(aw.Item).
+		Title("the workflow crashed " + info.ModTime().Format("Jan 2 15:04")).
+		Subtitle("↩ to open the crash log").
+		Arg(u.String()).
+		Quicklook(filename).
+		Icon(aw.IconWarning).
+		Valid(true)
+	it.NewModifier(aw.ModCmd).Arg(filename).Var("action", "copy").Valid(true).Subtitle("copy path: " + filename)
+	return withReportIssue(it, "the workflow crashed")
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// crash reports go to a sentry compatible store endpoint, only when
+// CRASH_REPORT_DSN is set. they carry the panic and its stack, or the error
+// a command failed with, and the workflow and macos versions, never the
+// arguments: those are what was searched for.
+
+const crashReportTimeout = time.Second * 3
+
+// quotedText is blanked out of panic messages, errors quote user input.
+var quotedText = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+}
+
+// reportCrash sends the recovered panic r to CRASH_REPORT_DSN. like
+// writeCrash it has to be called from the deferred recover.
+func reportCrash(r interface{}) error {
+	stack := make([]byte, 64<<10)
+	stack = stack[:runtime.Stack(stack, false)]
+	return sendReport(r, stackFrames(string(stack)))
+}
+
+// reportError sends err, which a command returned and ended the run with,
+// to CRASH_REPORT_DSN. it has no stack worth sending.
+func reportError(err error) error {
+	return sendReport(err, nil)
+}
+
+func sendReport(r interface{}, frames []sentryFrame) error {
+	dsn := config("CRASH_REPORT_DSN")
+	if dsn == "" {
+		return nil
+	}
+	endpoint, auth, err := parseDSN(dsn)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(crashEvent(r, frames))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), crashReportTimeout)
+	defer cancel()
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", auth)
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	return nil
+}
+
+// crashEvent is the sentry event for r, a panic or an error.
+func crashEvent(r interface{}, frames []sentryFrame) map[string]interface{} {
+	id := make([]byte, 16)
+	rand.Read(id)
+	exception := map[string]interface{}{
+		"type":  fmt.Sprintf("%T", r),
+		"value": quotedText.ReplaceAllString(fmt.Sprint(r), `"…"`),
+	}
+	if frames != nil {
+		exception["stacktrace"] = map[string]interface{}{"frames": frames}
+	}
+	return map[string]interface{}{
+		"event_id":  hex.EncodeToString(id),
+		"timestamp": time.Now().UTC().Format("2006-01-02T15:04:05"),
+		"platform":  "go",
+		"level":     "fatal",
+		"release":   binaryName + "@" + version,
+		"exception": map[string]interface{}{
+			"values": []interface{}{exception},
+		},
+		"contexts": map[string]interface{}{
+			"os":      map[string]string{"name": "macOS", "version": macOSVersion()},
+			"runtime": map[string]string{"name": "go", "version": runtime.Version()},
+		},
+		"tags": map[string]string{"commit": commit, "arch": runtime.GOARCH},
+	}
+}
+
+// parseDSN turns https://key@host/project into the project's store
+// endpoint and the auth header for it.
+func parseDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", err
+	}
+	project := strings.Trim(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || project == "" {
+		return "", "", fmt.Errorf("%q is not a dsn, expected https://key@host/project", dsn)
+	}
+	i := strings.LastIndexByte(project, '/')
+	endpoint := fmt.Sprintf("%s://%s/%sapi/%s/store/", u.Scheme, u.Host, project[:i+1], project[i+1:])
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s/%s, sentry_key=%s", binaryName, version, u.User.Username())
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	return endpoint, auth, nil
+}
+
+// stackFrames parses a runtime.Stack dump into frames, outermost first as
+// sentry wants them.
+func stackFrames(stack string) []sentryFrame {
+	lines := strings.Split(stack, "\n")
+	var frames []sentryFrame
+	for i := 1; i+1 < len(lines); i += 2 {
+		fn := lines[i]
+		loc := strings.TrimSpace(lines[i+1])
+		if j := strings.LastIndex(fn, "("); j > 0 {
+			fn = fn[:j]
+		}
+		if j := strings.LastIndex(loc, " +0x"); j > 0 {
+			loc = loc[:j]
+		}
+		j := strings.LastIndexByte(loc, ':')
+		if j < 0 {
+			continue
+		}
+		line, _ := strconv.Atoi(loc[j+1:])
+		frames = append([]sentryFrame{{Function: fn, Filename: loc[:j], Lineno: line}}, frames...)
+	}
+	return frames
+}
+
+func macOSVersion() string {
+	out, err := exec.Command("sw_vers", "-productVersion").Output()
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func checkDSN(v string) error {
+	if v == "" {
+		return nil
+	}
+	_, _, err := parseDSN(v)
+	return err
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exchange rates come from open.er-api.com, a free api that needs no key.
+// they are refreshed daily, approximate is all HOME_CURRENCY promises.
+const (
+	ratesURL    = "https://open.er-api.com/v6/latest/USD"
+	ratesFile   = "exchange-rates.json"
+	ratesMaxAge = time.Hour * 24
+)
+
+// currencySymbols are the prefixes used for the home currency price, any
+// other currency is written with its code.
+var currencySymbols = map[string]string{
+	"USD": "$", "EUR": "€", "GBP": "£", "JPY": "¥", "CNY": "¥", "KRW": "₩",
+	"INR": "₹", "AUD": "A$", "CAD": "C$", "NZD": "NZ$", "CHF": "CHF ",
+}
+
+func init() {
+	registerRefreshJob("exchange-rates", func(ctx context.Context) error {
+		if config("HOME_CURRENCY") == "" {
+			return nil
+		}
+		rates, err := fetchRates(ctx)
+		if err != nil {
+			return err
+		}
+		return cache().StoreJSON(ratesFile, rates)
+	})
+}
+
+var (
+	ratesOnce sync.Once
+	rates     map[string]float64
+)
+
+// exchangeRates maps currency codes to their rate against the dollar.
+func exchangeRates() map[string]float64 {
+	ratesOnce.Do(func() {
+		err := cache().LoadOrStoreJSON(ratesFile, ratesMaxAge, func() (interface{}, error) {
+			return fetchRates(context.Background())
+		}, &rates)
+		if err != nil {
+			debug("no exchange rates: %s", err.Error())
+		}
+	})
+	return rates
+}
+
+func fetchRates(ctx context.Context) (map[string]float64, error) {
+	req, err := http.NewRequest("GET", ratesURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	debug("sending request: %s %s", req.Method, req.URL.String())
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	var body struct {
+		Result string             `json:"result"`
+		Rates  map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.Result != "success" || len(body.Rates) == 0 {
+		return nil, fmt.Errorf("exchange rate api returned no rates")
+	}
+	return body.Rates, nil
+}
+
+// homePrice is res's price converted to HOME_CURRENCY, like "~$8.10", or ""
+// when there is nothing to convert.
+func homePrice(res app) string {
+	home := strings.ToUpper(config("HOME_CURRENCY"))
+	if home == "" || res.Price <= 0 || res.Currency == "" || strings.EqualFold(res.Currency, home) {
+		return ""
+	}
+	r := exchangeRates()
+	from, to := r[strings.ToUpper(res.Currency)], r[home]
+	if from == 0 || to == 0 {
+		return ""
+	}
+	v := res.Price / from * to
+	if sym, ok := currencySymbols[home]; ok {
+		return fmt.Sprintf("~%s%.2f", sym, v)
+	}
+	return fmt.Sprintf("~%.2f %s", v, home)
+}
+
+func checkCurrency(v string) error {
+	if v == "" {
+		return nil
+	}
+	if len(v) != 3 || strings.ToUpper(v) != strings.Map(func(r rune) rune {
+		if r < 'A' || r > 'Z' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(v)) {
+		return fmt.Errorf("%q is not a three letter currency code", v)
+	}
+	return nil
+}
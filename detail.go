@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+// maxInlineLanguages is how many language codes fit in an item title before
+// the rest get summarized as "+N more".
+const maxInlineLanguages = 8
+
+// detailFeedback is the drill-down view for a single app, reached by tabbing
+// on a search result (which autocompletes to "id:<trackId>").
+func detailFeedback(ctx context.Context, id int64) (*aw.Feedback, error) {
+	fb := aw.NewFeedback()
+	res, err := lookup(ctx, id)
+	if isRateLimit(err) {
+		fb.Items = append(fb.Items, limitItem(err, false))
+		return fb, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		if countries, other := availableIn(ctx, id); other != nil {
+			fb.Items = append(fb.Items, storefrontHintItem(*other, countries))
+			return fb, nil
+		}
+		fb.NewItem(fmt.Sprintf("no app found with id %d", id)).
+			Icon(aw.IconWarning).
+			Valid(false)
+		return fb, nil
+	}
+	enriched := []app{*res}
+	enrich(ctx, enriched)
+	res = &enriched[0]
+	recordViewed(*res)
+	icon := downloadAllImages(ctx, downloadConcurrency(), []string{res.Artwork})[0]
+	fb.Items = append(fb.Items, appItem(*res).Icon(icon))
+	fb.Items = append(fb.Items, ratingItems(ctx, *res)...)
+	items := detailItems(*res)
+	if rank := genreRank(ctx, *res); rank != "" {
+		name := "top free"
+		if res.Price > 0 {
+			name = "top paid"
+		}
+		items = append(items, new(aw.Item).
+			Title(rank).
+			Subtitle(fmt.Sprintf("%s chart rank in %s", name, strings.ToUpper(config("COUNTRY")))).
+			Valid(false))
+	}
+	if it := vppItem(ctx, res.ID); it != nil {
+		items = append(items, it)
+	}
+	for _, it := range items {
+		fb.Items = append(fb.Items, it.Icon(aw.IconInfo))
+	}
+	return fb, nil
+}
+
+func detailItems(res app) []*aw.Item {
+	var items []*aw.Item
+	add := func(title, subtitle string) *aw.Item {
+		it := new(aw.Item).
+			Title(title).
+			Subtitle(subtitle).
+			Copytext(title).
+			Largetype(title).
+			Valid(false)
+		items = append(items, it)
+		return it
+	}
+	if res.Developer != "" {
+		add(res.Developer, "developer")
+	}
+	if res.Version != "" {
+		add(res.Version, "version")
+	}
+	if !res.Updated.IsZero() {
+		sub := "last updated"
+		if res.stale() {
+			sub = staleMark + " not updated in a long time, may be abandoned"
+		}
+		add(res.Updated.Format("Jan 2, 2006")+" ("+relativeTime(res.Updated)+")", sub)
+	}
+	if n := res.size(); n > 0 {
+		add(humanSize(n), "size")
+	}
+	if res.Genre != "" {
+		add(res.Genre, "category")
+	}
+	if res.isGame() {
+		if res.GameCenter {
+			add("Game Center", "supports multiplayer, leaderboards and achievements")
+		} else {
+			add("No Game Center", "no game center multiplayer, leaderboards or achievements")
+		}
+	}
+	if notes := strings.TrimSpace(res.ReleaseNotes); notes != "" {
+		add(firstLine(notes), strings.TrimSpace("what's new "+res.Version)+", ⌘L to read it all").
+			Copytext(notes).
+			Largetype(notes)
+	}
+	if desc := strings.TrimSpace(res.Description); desc != "" {
+		add(firstLine(desc), "description, ⌘L to read it all").
+			Copytext(desc).
+			Largetype(desc)
+	}
+	if n := len(res.Screenshots); n > 0 {
+		add(fmt.Sprintf("%d screenshots", n), "⇧ to preview the first").
+			Copytext(strings.Join(res.Screenshots, "\n")).
+			Quicklook(res.Screenshots[0])
+	}
+	if res.EditorialNote != "" {
+		add(res.EditorialNote, "editorial notes")
+	}
+	if len(res.InAppPurchases) > 0 {
+		all := strings.Join(res.InAppPurchases, "\n")
+		add(strings.Join(res.InAppPurchases, ", "), fmt.Sprintf("%d in-app purchases", len(res.InAppPurchases))).
+			Copytext(all).
+			Largetype(all)
+	}
+	if len(res.Languages) > 0 {
+		all := strings.Join(res.Languages, ", ")
+		add(languageSummary(res.Languages), fmt.Sprintf("%d languages", len(res.Languages))).
+			Copytext(all).
+			Largetype(all)
+	}
+	items = append(items, new(aw.Item).
+		Title("Privacy").
+		Subtitle("⇥ to see what data the app collects").
+		Autocomplete(fmt.Sprintf("privacy:%d", res.ID)).
+		Valid(false))
+	return items
+}
+
+func languageSummary(codes []string) string {
+	if len(codes) <= maxInlineLanguages {
+		return strings.Join(codes, ", ")
+	}
+	return fmt.Sprintf(
+		"%s (+%d more)",
+		strings.Join(codes[:maxInlineLanguages], ", "),
+		len(codes)-maxInlineLanguages,
+	)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+// the wishlist check logs every change it sees, and once a week those are
+// written up as a markdown digest.
+const (
+	wishlistChangesFile = "wishlist-changes.json"
+	digestInterval      = time.Hour * 24 * 7
+)
+
+const (
+	changePrice   = "price"
+	changeVersion = "version"
+	changeRating  = "rating"
+)
+
+type wishlistChange struct {
+	Time time.Time `json:"time"`
+	ID   int64     `json:"id"`
+	Name string    `json:"name"`
+	URL  string    `json:"url"`
+	Kind string    `json:"kind"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+}
+
+type wishlistChangeLog struct {
+	LastDigest time.Time        `json:"lastDigest"`
+	Changes    []wishlistChange `json:"changes"`
+}
+
+func loadWishlistChanges() (wishlistChangeLog, error) {
+	var l wishlistChangeLog
+	c := aw.NewCache(dataDir())
+	if !c.Exists(wishlistChangesFile) {
+		return l, nil
+	}
+	return l, c.LoadJSON(wishlistChangesFile, &l)
+}
+
+func saveWishlistChanges(l wishlistChangeLog) error {
+	return aw.NewCache(dataDir()).StoreJSON(wishlistChangesFile, l)
+}
+
+func logWishlistChanges(changes []wishlistChange) error {
+	l, err := loadWishlistChanges()
+	if err != nil {
+		return err
+	}
+	if l.LastDigest.IsZero() {
+		// the first week starts now, not at the beginning of time
+		l.LastDigest = time.Now()
+	}
+	l.Changes = append(l.Changes, changes...)
+	return saveWishlistChanges(l)
+}
+
+// maybeWriteDigest writes the digest of the week's changes to DIGEST_DIR
+// once a week has passed since the last one, and notifies about it.
+func maybeWriteDigest(ctx context.Context) error {
+	l, err := loadWishlistChanges()
+	if err != nil {
+		return err
+	}
+	if time.Since(l.LastDigest) < digestInterval {
+		return nil
+	}
+	now := time.Now()
+	dir := config("DIGEST_DIR")
+	if dir == "" {
+		dir = filepath.Join(dataDir(), "digests")
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, "wishlist-"+now.Format("2006-01-02")+".md")
+	if err := ioutil.WriteFile(filename, renderDigest(l, now), 0644); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("%d changes this week", len(l.Changes))
+	l.LastDigest, l.Changes = now, nil
+	if err := saveWishlistChanges(l); err != nil {
+		return err
+	}
+	u := &url.URL{Scheme: "file", Path: filename}
+	if err := notify(ctx, eventDigest, "Wishlist digest", message, u.String()); err != nil {
+		debug("%s", err.Error())
+	}
+	return nil
+}
+
+// renderDigest writes l's changes up as markdown, grouped by kind.
+func renderDigest(l wishlistChangeLog, until time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# wishlist digest, %s – %s\n", l.LastDigest.Format("Jan 2"), until.Format("Jan 2 2006"))
+	if len(l.Changes) == 0 {
+		buf.WriteString("\nnothing on the wishlist changed.\n")
+		return buf.Bytes()
+	}
+	for _, section := range []struct{ kind, title string }{
+		{changePrice, "prices"},
+		{changeVersion, "new versions"},
+		{changeRating, "ratings"},
+	} {
+		var wrote bool
+		for _, c := range l.Changes {
+			if c.Kind != section.kind {
+				continue
+			}
+			if !wrote {
+				fmt.Fprintf(&buf, "\n## %s\n\n", section.title)
+				wrote = true
+			}
+			fmt.Fprintf(&buf, "- [%s](%s): %s → %s (%s)\n", c.Name, c.URL, c.From, c.To, c.Time.Format("Jan 2"))
+		}
+	}
+	return buf.Bytes()
+}
+
+// wishlistDigestCmd prints the digest of the changes logged so far, without
+// waiting for the week to be up.
+func wishlistDigestCmd(args []string) error {
+	fs := flag.NewFlagSet("wishlist digest", flag.ContinueOnError)
+	out := fs.String("o", "", "write to file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	l, err := loadWishlistChanges()
+	if err != nil {
+		return err
+	}
+	digest := renderDigest(l, time.Now())
+	if *out == "" {
+		_, err := os.Stdout.Write(digest)
+		return err
+	}
+	return ioutil.WriteFile(*out, digest, 0644)
+}
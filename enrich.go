@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// enrichTTL is how long looked up details are kept. descriptions and
+	// screenshots rarely change, release notes change with each version and
+	// are compared against it.
+	enrichTTL = time.Hour * 24 * 7
+	// how many apps go into one lookup request, and how many of those
+	// requests run at once. every request takes a token from the shared
+	// rate limit bucket, so this stays small.
+	enrichBatch       = 10
+	enrichConcurrency = 2
+	// enrichTimeout caps how long a search waits on enrichment, apps whose
+	// lookups take longer are shown without the extra details.
+	enrichTimeout = time.Second * 2
+)
+
+// enrichment holds the details of an app that only a lookup is sure to
+// return: search results from the amp backend, charts and the local index
+// come without them.
+type enrichment struct {
+	Version      string   `json:"version"`
+	Description  string   `json:"description"`
+	ReleaseNotes string   `json:"releaseNotes"`
+	Screenshots  []string `json:"screenshots"`
+}
+
+const enrichCachePrefix = "enriched-"
+
+func enrichCacheKey(id int64) string {
+	return fmt.Sprintf("%s%d.json", enrichCachePrefix, id)
+}
+
+func (e enrichment) apply(res *app) {
+	if res.Description == "" {
+		res.Description = e.Description
+	}
+	if res.ReleaseNotes == "" && (res.Version == "" || res.Version == e.Version) {
+		res.ReleaseNotes = e.ReleaseNotes
+	}
+	if len(res.Screenshots) == 0 {
+		res.Screenshots = e.Screenshots
+	}
+}
+
+// needsEnrichment reports whether res is an app store app missing the
+// details a lookup would add.
+func needsEnrichment(res app) bool {
+	return res.Source == "" && res.ID != 0 && res.Description == "" && res.ReleaseNotes == "" && len(res.Screenshots) == 0
+}
+
+// enrich fills in descriptions, release notes and screenshots of results
+// that came without them, from cache where possible and by looking up the
+// rest in batches. it gives up quietly when rate limited or out of time,
+// enrichment is never worth failing a search over.
+func enrich(ctx context.Context, results []app) {
+	c := cache()
+	var missing []int64
+	index := map[int64][]int{}
+	for i, res := range results {
+		if !needsEnrichment(res) {
+			continue
+		}
+		key := enrichCacheKey(res.ID)
+		if c.Exists(key) && !c.Expired(key, enrichTTL) {
+			var e enrichment
+			if err := c.LoadJSON(key, &e); err == nil {
+				e.apply(&results[i])
+				continue
+			}
+		}
+		if _, ok := index[res.ID]; !ok {
+			missing = append(missing, res.ID)
+		}
+		index[res.ID] = append(index[res.ID], i)
+	}
+	if len(missing) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, enrichTimeout)
+	defer cancel()
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan bool, enrichConcurrency)
+	)
+	for start := 0; start < len(missing); start += enrichBatch {
+		end := start + enrichBatch
+		if end > len(missing) {
+			end = len(missing)
+		}
+		wg.Add(1)
+		go func(ids []int64) {
+			defer wg.Done()
+			sem <- true
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			looked, err := lookupMany(ctx, ids)
+			if err != nil {
+				debug("failed to enrich %d apps: %s", len(ids), err.Error())
+				return
+			}
+			for _, l := range looked {
+				e := enrichment{
+					Version:      l.Version,
+					Description:  l.Description,
+					ReleaseNotes: l.ReleaseNotes,
+					Screenshots:  l.Screenshots,
+				}
+				if !private() {
+					if err := c.StoreJSON(enrichCacheKey(l.ID), e); err != nil {
+						debug("failed to cache details of %d: %s", l.ID, err.Error())
+					}
+				}
+				mu.Lock()
+				for _, i := range index[l.ID] {
+					e.apply(&results[i])
+				}
+				mu.Unlock()
+			}
+		}(missing[start:end])
+	}
+	wg.Wait()
+}
+
+// enrichedFields are the subtitle fields that need enrich to have run.
+var enrichedFields = []string{"whatsnew", "summary"}
+
+// wantsEnrichment reports whether SUBTITLE_FIELDS shows anything enrich
+// adds, searches only spend lookups on it then.
+func wantsEnrichment() bool {
+	for _, name := range strings.Split(config("SUBTITLE_FIELDS"), ",") {
+		for _, f := range enrichedFields {
+			if strings.TrimSpace(name) == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// firstLine is the first line or sentence of s, for a subtitle.
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, ". "); i >= 0 {
+		s = s[:i+1]
+	}
+	return strings.TrimLeft(strings.TrimSpace(s), "•-* ")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+func init() {
+	registerAction(action{
+		name: "export-json",
+		desc: "Export full metadata as json",
+		arg:  func(res app) string { return fmt.Sprint(res.ID) },
+		run:  exportJSON,
+	})
+}
+
+var unsafeFilename = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// exportJSON looks the app up again and saves every field apple returns,
+// either to a file in EXPORT_DIR (revealed in finder) or to the clipboard,
+// depending on EXPORT_TARGET.
+func exportJSON(ctx context.Context, arg string) error {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not an app id", arg)
+	}
+	params := url.Values{}
+	params.Set("id", arg)
+	params.Set("country", config("COUNTRY"))
+	raw, err := itunesRaw(ctx, "lookup", params)
+	if err != nil {
+		return err
+	}
+	if len(raw) == 0 {
+		return fmt.Errorf("no app found with id %d", id)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw[0], "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	if config("EXPORT_TARGET") == "clipboard" {
+		return copyToClipboard(ctx, buf.String())
+	}
+	var res app
+	json.Unmarshal(raw[0], &res)
+	dir := config("EXPORT_DIR")
+	if dir == "" {
+		dir = filepath.Join(homeDir(), "Downloads")
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, fmt.Sprintf("%s-%d.json", unsafeFilename.ReplaceAllString(res.Name, "_"), id))
+	if err := ioutil.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return exec.CommandContext(ctx, "open", "-R", filename).Run()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+func init() {
+	register(command{
+		name:  "fallback",
+		usage: "fallback <term>",
+		desc:  "open the mac app store's own search for term, for alfred's fallback searches",
+		run:   fallbackCmd,
+	})
+	commandAliases["--fallback"] = "fallback"
+}
+
+// storeSearchURL opens the mac app store app searching for term.
+func storeSearchURL(term string) string {
+	return "macappstore://search.itunes.apple.com/WebObjects/MZSearch.woa/wa/search?" + url.Values{
+		"q": {term},
+	}.Encode()
+}
+
+// storeSearchItem ends a list of results with the app store's own search
+// for term, which finds more than the api hands back.
+func storeSearchItem(term string) *aw.Item {
+	return new(aw.Item).
+		Title("Search in the App Store app").
+		Subtitle(fmt.Sprintf("↩ to search for %q in the mac app store", term)).
+		Arg(storeSearchURL(term)).
+		Icon(&aw.Icon{Value: "/System/Applications/App Store.app", Type: aw.IconTypeFileIcon}).
+		Valid(true)
+}
+
+// fallbackCmd skips the script filter altogether: alfred's fallback searches
+// hand over whatever was typed and the app store takes it from there.
+func fallbackCmd(ctx context.Context, args []string) error {
+	term := strings.TrimSpace(strings.Join(args, " "))
+	if term == "" {
+		return fmt.Errorf("search term is required")
+	}
+	return openURL(ctx, storeSearchURL(term))
+}
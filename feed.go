@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+// new versions of wishlist apps are also kept as an atom feed, for feed
+// readers pointed at the file.
+const (
+	changelogFile = "changelog.json"
+	feedFile      = "changelog.atom"
+	// the feed keeps this many releases, oldest dropped first
+	feedSize = 100
+)
+
+type release struct {
+	ID      int64     `json:"id"`
+	Name    string    `json:"name"`
+	URL     string    `json:"url"`
+	Version string    `json:"version"`
+	Notes   string    `json:"notes"`
+	Date    time.Time `json:"date"`
+}
+
+func loadChangelog() ([]release, error) {
+	var releases []release
+	c := aw.NewCache(dataDir())
+	if !c.Exists(changelogFile) {
+		return nil, nil
+	}
+	return releases, c.LoadJSON(changelogFile, &releases)
+}
+
+// logReleases adds new versions to the changelog, newest first, and writes
+// the feed out again.
+func logReleases(releases []release) error {
+	if len(releases) == 0 && fileExists(feedPath()) {
+		return nil
+	}
+	old, err := loadChangelog()
+	if err != nil {
+		return err
+	}
+	all := append(releases, old...)
+	if len(all) > feedSize {
+		all = all[:feedSize]
+	}
+	if err := aw.NewCache(dataDir()).StoreJSON(changelogFile, all); err != nil {
+		return err
+	}
+	return writeFeed(feedPath(), all)
+}
+
+// feedPath is where the feed is written, FEED_FILE or changelog.atom in the
+// workflow data dir.
+func feedPath() string {
+	if f := config("FEED_FILE"); f != "" {
+		return f
+	}
+	return filepath.Join(dataDir(), feedFile)
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Content atomText `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+func renderFeed(releases []release) ([]byte, error) {
+	feed := atomFeed{
+		ID:      "urn:" + bundleID + ":changelog",
+		Title:   "Wishlist app updates",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  binaryName,
+		Link:    atomLink{Href: "https://github.com/nkcmr/alfred-apple-app-search"},
+	}
+	if len(releases) > 0 {
+		feed.Updated = releases[0].Date.UTC().Format(time.RFC3339)
+	}
+	for _, r := range releases {
+		notes := r.Notes
+		if notes == "" {
+			notes = "no release notes"
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      fmt.Sprintf("urn:%s:release:%d:%s", bundleID, r.ID, r.Version),
+			Title:   r.Name + " " + r.Version,
+			Updated: r.Date.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: r.URL, Rel: "alternate"},
+			Content: atomText{Type: "text", Body: notes},
+		})
+	}
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+func writeFeed(filename string, releases []release) error {
+	data, err := renderFeed(releases)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// wishlistFeedCmd prints the feed, or writes it to a file.
+func wishlistFeedCmd(args []string) error {
+	fs := flag.NewFlagSet("wishlist feed", flag.ContinueOnError)
+	out := fs.String("o", "", "write to file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	releases, err := loadChangelog()
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		return writeFeed(*out, releases)
+	}
+	data, err := renderFeed(releases)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
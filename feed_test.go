@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestRenderFeed(t *testing.T) {
+	date := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	data, err := renderFeed([]release{
+		{ID: 904280696, Name: "Things 3", URL: "https://apps.apple.com/app/id904280696", Version: "3.20", Notes: "bug fixes & <improvements>", Date: date},
+		{ID: 1, Name: "Other", Version: "1.0", Date: date.Add(-time.Hour)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatalf("feed does not parse back: %s\n%s", err, data)
+	}
+	if feed.Updated != "2024-03-01T12:00:00Z" {
+		t.Errorf("feed updated = %q, want the newest release's date", feed.Updated)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(feed.Entries))
+	}
+	e := feed.Entries[0]
+	if e.Title != "Things 3 3.20" || e.Content.Body != "bug fixes & <improvements>" || e.Link.Href != "https://apps.apple.com/app/id904280696" {
+		t.Errorf("first entry = %+v", e)
+	}
+	if feed.Entries[1].Content.Body != "no release notes" {
+		t.Errorf("entry without notes has content %q", feed.Entries[1].Content.Body)
+	}
+	if feed.Entries[0].ID == feed.Entries[1].ID {
+		t.Errorf("entries share the id %q", e.ID)
+	}
+}
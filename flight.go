@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// flightGroup runs one call per key at a time, later callers for the same
+// key waiting for and sharing the first one's result.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg  sync.WaitGroup
+	val string
+	err error
+}
+
+func (g *flightGroup) do(key string, fn func() (string, error)) (string, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*flightCall{}
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := new(flightCall)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.val, c.err = fn()
+	c.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	return c.val, c.err
+}
+
+var iconDownloads flightGroup
+
+// iconFlightTTL is how recent another process's download of a url has to
+// be for us to use it instead of downloading it again.
+const iconFlightTTL = time.Minute
+
+// fetchIcon downloads url into cache, at most once at a time however many
+// results in however many running processes want it. within a process that
+// is a flightGroup, across processes an flock on a file per url, which the
+// winner leaves the finished entry in for the others to pick up.
+func fetchIcon(ctx context.Context, cache *iconCache, url string) (string, error) {
+	return iconDownloads.do(url, func() (string, error) {
+		if err := os.MkdirAll(cache.dir, os.ModePerm); err != nil {
+			return "", err
+		}
+		f, err := os.OpenFile(filepath.Join(cache.dir, ".inflight-"+md5hash(url)), os.O_CREATE|os.O_RDWR, 0600)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+			return "", err
+		}
+		defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+		var done iconEntry
+		if data, err := ioutil.ReadAll(f); err == nil && json.Unmarshal(data, &done) == nil {
+			if time.Since(done.Checked) < iconFlightTTL {
+				if filename, ok := cache.adopt(url, done); ok {
+					debug("downloaded by another process: %s", url)
+					return filename, nil
+				}
+			}
+		}
+		filename, err := downloadIcon(ctx, cache, url)
+		if err != nil {
+			return "", err
+		}
+		if _, e, ok := cache.entry(url); ok {
+			data, _ := json.Marshal(e)
+			if err := f.Truncate(0); err == nil {
+				f.WriteAt(data, 0)
+			}
+		}
+		return filename, nil
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// humanSize formats a byte count the way the app store does, in decimal
+// units: "85 MB", "1.2 GB".
+func humanSize(bytes int64) string {
+	const unit = 1000
+	if bytes < unit {
+		return fmt.Sprintf("%d bytes", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	v := float64(bytes) / float64(div)
+	prec := 1
+	if v >= 10 {
+		prec = 0
+	}
+	return strconv.FormatFloat(v, 'f', prec, 64) + " " + string("KMGTPE"[exp]) + "B"
+}
+
+// groupDigits writes n with thousands separators: "1,234".
+func groupDigits(n int64) string {
+	if n < 0 {
+		return "-" + groupDigits(-n)
+	}
+	s := strconv.FormatInt(n, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// compactCount shortens large counts the way the app store does: "950",
+// "12.3k", "1.2M".
+func compactCount(n int64) string {
+	if n < 0 {
+		return "-" + compactCount(-n)
+	}
+	for _, u := range []struct {
+		div    float64
+		suffix string
+	}{{1e9, "B"}, {1e6, "M"}, {1e3, "k"}} {
+		// anything that would round up to 1000 of the next unit down
+		// belongs to this one
+		if float64(n) < u.div-u.div/2000 {
+			continue
+		}
+		v := float64(n) / u.div
+		prec := 1
+		if v >= 100 {
+			prec = 0
+		}
+		return strings.TrimSuffix(strconv.FormatFloat(v, 'f', prec, 64), ".0") + u.suffix
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// relativeTime renders t as a rough distance from now: "3 weeks ago".
+func relativeTime(t time.Time) string {
+	d := time.Since(t)
+	day := time.Hour * 24
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit + " ago"
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < day:
+		return plural(int(d/time.Hour), "hour")
+	case d < day*7:
+		return plural(int(d/day), "day")
+	case d < day*30:
+		return plural(int(d/(day*7)), "week")
+	case d < day*365:
+		return plural(int(d/(day*30)), "month")
+	default:
+		return plural(int(d/(day*365)), "year")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const fossFile = "foss.json"
+
+type fossAlternative struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// fossAlternatives maps well known proprietary apps (by name, lower case,
+// without the tagline) to an open source app that does the same job. the
+// FOSS_ALTERNATIVES_URL setting can add to and override these.
+var fossAlternatives = map[string]fossAlternative{
+	"adobe photoshop":      {"GIMP", "https://github.com/GNOME/gimp"},
+	"pixelmator pro":       {"GIMP", "https://github.com/GNOME/gimp"},
+	"sketch":               {"Penpot", "https://github.com/penpot/penpot"},
+	"final cut pro":        {"Shotcut", "https://github.com/mltframework/shotcut"},
+	"logic pro":            {"Ardour", "https://github.com/Ardour/ardour"},
+	"microsoft word":       {"LibreOffice", "https://github.com/LibreOffice/core"},
+	"microsoft excel":      {"LibreOffice", "https://github.com/LibreOffice/core"},
+	"microsoft powerpoint": {"LibreOffice", "https://github.com/LibreOffice/core"},
+	"1password":            {"KeePassXC", "https://github.com/keepassxreboot/keepassxc"},
+	"magnet":               {"Rectangle", "https://github.com/rxhanson/Rectangle"},
+	"bartender 5":          {"Ice", "https://github.com/jordanbaird/Ice"},
+	"amphetamine":          {"KeepingYouAwake", "https://github.com/newmarcel/KeepingYouAwake"},
+	"bear":                 {"Joplin", "https://github.com/laurent22/joplin"},
+	"transmit 5":           {"Cyberduck", "https://github.com/iterate-ch/cyberduck"},
+	"parallels desktop":    {"UTM", "https://github.com/utmapp/UTM"},
+	"istat menus":          {"Stats", "https://github.com/exelban/stats"},
+	"infuse 7":             {"IINA", "https://github.com/iina/iina"},
+	"tableplus":            {"DBeaver", "https://github.com/dbeaver/dbeaver"},
+}
+
+var (
+	fossOnce   sync.Once
+	fossLoaded map[string]fossAlternative
+)
+
+// fossAlternativeFor returns the open source alternative to res, if one is
+// known. the remote list is only read from cache, the foss refresh job
+// keeps it current.
+func fossAlternativeFor(res app) (fossAlternative, bool) {
+	fossOnce.Do(func() {
+		fossLoaded = map[string]fossAlternative{}
+		for k, v := range fossAlternatives {
+			fossLoaded[k] = v
+		}
+		var remote map[string]fossAlternative
+		c := cache()
+		if c.Exists(fossFile) && c.LoadJSON(fossFile, &remote) == nil {
+			for k, v := range remote {
+				fossLoaded[normalizeQuery(k)] = v
+			}
+		}
+	})
+	if res.Source != "" {
+		return fossAlternative{}, false
+	}
+	alt, ok := fossLoaded[baseName(res.Name)]
+	return alt, ok
+}
+
+// baseName is an app's normalized name without the tagline app store names
+// tend to carry: "Bartender 5 - Menu Bar Tool" is "bartender 5".
+func baseName(name string) string {
+	name = normalizeQuery(name)
+	for _, sep := range []string{" - ", " – ", " — ", ": "} {
+		if i := strings.Index(name, sep); i > 0 {
+			name = name[:i]
+		}
+	}
+	return name
+}
+
+func init() {
+	registerAction(action{
+		name: "open-alternative",
+		desc: "Open the open source alternative",
+		arg: func(res app) string {
+			alt, _ := fossAlternativeFor(res)
+			return alt.URL
+		},
+		subtitle: func(res app) string {
+			if alt, ok := fossAlternativeFor(res); ok {
+				return "Open " + alt.Name + ", an open source alternative"
+			}
+			return "No open source alternative known"
+		},
+		run: openURL,
+	})
+	registerRefreshJob("foss", func(ctx context.Context) error {
+		u := config("FOSS_ALTERNATIVES_URL")
+		if u == "" {
+			return nil
+		}
+		req, err := http.NewRequest("GET", u, http.NoBody)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+		}
+		var remote map[string]fossAlternative
+		if err := json.NewDecoder(resp.Body).Decode(&remote); err != nil {
+			return err
+		}
+		return cache().StoreJSON(fossFile, remote)
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const (
+	frecencyFile = "frecency.json"
+
+	// a pick loses half its weight every frecencyHalfLife
+	frecencyHalfLife = time.Hour * 24 * 14
+	// how much a pick of one app boosts the rest of its developer's apps
+	developerWeight = 0.5
+)
+
+type frecencyEntry struct {
+	Score float64   `json:"score"`
+	Last  time.Time `json:"last"`
+}
+
+// decayed returns the entry's score as of now.
+func (e frecencyEntry) decayed(now time.Time) float64 {
+	age := now.Sub(e.Last)
+	return e.Score * math.Pow(0.5, float64(age)/float64(frecencyHalfLife))
+}
+
+type frecency struct {
+	Apps       map[string]frecencyEntry `json:"apps"`
+	Developers map[string]frecencyEntry `json:"developers"`
+}
+
+func loadFrecency() frecency {
+	f := frecency{
+		Apps:       map[string]frecencyEntry{},
+		Developers: map[string]frecencyEntry{},
+	}
+	c := aw.NewCache(dataDir())
+	if c.Exists(frecencyFile) {
+		if err := c.LoadJSON(frecencyFile, &f); err != nil {
+			debug("failed to load frecency: %s", err.Error())
+		}
+	}
+	return f
+}
+
+func bump(m map[string]frecencyEntry, key string, now time.Time) {
+	e := m[key]
+	m[key] = frecencyEntry{Score: e.decayed(now) + 1, Last: now}
+}
+
+// recordAction notes that the user picked an app from the results.
+func recordAction(id, developer string) {
+	if private() {
+		return
+	}
+	f := loadFrecency()
+	now := time.Now()
+	bump(f.Apps, id, now)
+	if developer != "" {
+		bump(f.Developers, developer, now)
+	}
+	if err := aw.NewCache(dataDir()).StoreJSON(frecencyFile, f); err != nil {
+		debug("failed to store frecency: %s", err.Error())
+	}
+}
+
+// rerank moves apps the user has picked before (and apps by developers they
+// have picked from) towards the top. apps without any history keep the
+// order apple returned them in.
+func rerank(results []app) {
+	f := loadFrecency()
+	if len(f.Apps) == 0 && len(f.Developers) == 0 {
+		return
+	}
+	now := time.Now()
+	score := func(res app) float64 {
+		return f.Apps[strconv.FormatInt(res.ID, 10)].decayed(now) +
+			f.Developers[res.Developer].decayed(now)*developerWeight
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return score(results[i]) > score(results[j])
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+// searchDescriptions full text searches the descriptions of every app the
+// local index has seen, best match first, returning each app along with
+// the bit of its description that matched.
+func searchDescriptions(term string, limit int) ([]app, []string, error) {
+	var words []string
+	for _, w := range strings.Fields(term) {
+		// each word as a prefix match, quoted so fts5 does not read
+		// operators into it
+		words = append(words, `"`+strings.Replace(w, `"`, `""`, -1)+`"*`)
+	}
+	if len(words) == 0 {
+		return nil, nil, nil
+	}
+	rows, err := sqlite(fmt.Sprintf(
+		"SELECT apps.data, snippet(descriptions, 3, '', '', '…', 12) FROM descriptions JOIN apps ON apps.id = descriptions.id WHERE descriptions MATCH %s ORDER BY bm25(descriptions, 0, 10, 5, 1) LIMIT %d;\n",
+		sqlQuote(strings.Join(words, " AND ")),
+		limit,
+	))
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		apps     []app
+		snippets []string
+	)
+	for _, row := range rows {
+		var res app
+		if len(row) < 2 || json.Unmarshal([]byte(row[0]), &res) != nil {
+			continue
+		}
+		apps = append(apps, res)
+		snippets = append(snippets, strings.Join(strings.Fields(row[1]), " "))
+	}
+	return apps, snippets, nil
+}
+
+// descFeedback lists the apps whose descriptions match term, with the
+// matching passage as the subtitle. it only ever looks at the local index,
+// so it works offline.
+func descFeedback(ctx context.Context, fb *aw.Feedback, term string) {
+	if strings.TrimSpace(term) == "" {
+		fb.NewItem("search app descriptions").
+			Subtitle("type words from the description of an app you have come across before").
+			Icon(aw.IconInfo).
+			Valid(false)
+		return
+	}
+	apps, snippets, err := searchDescriptions(term, configInt("RESULT_LIMIT"))
+	if err != nil {
+		debug("failed to search descriptions: %s", err.Error())
+	}
+	var shown []app
+	var shownSnippets []string
+	for _, i := range unblockedIndexes(apps) {
+		shown = append(shown, apps[i])
+		shownSnippets = append(shownSnippets, snippets[i])
+	}
+	if len(shown) == 0 {
+		fb.NewItem("no descriptions match").
+			Subtitle("only apps the workflow has already seen in results or looked up are indexed").
+			Icon(aw.IconInfo).
+			Valid(false)
+		return
+	}
+	offset := len(fb.Items)
+	appendApps(ctx, fb, shown)
+	for i, it := range fb.Items[offset:] {
+		it.Subtitle(shownSnippets[i])
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+func init() {
+	registerAction(action{
+		name: "refresh-search",
+		desc: "Search again without the cache",
+		run:  refreshSearch,
+	})
+}
+
+// headerItem sums up a search above its results: how many there are, where
+// they are from and how old they are. actioning it runs the search again
+// straight from the provider.
+func headerItem(raw string, q query, n int, err error) *aw.Item {
+	parts := []string{fmt.Sprintf("%d results", n)}
+	if n == 1 {
+		parts[0] = "1 result"
+	}
+	if p := q.provider(); p.name() == appStoreProvider {
+		parts = append(parts, strings.ToUpper(strings.Join(searchCountries(), "+"))+" store")
+	} else {
+		parts = append(parts, p.label())
+	}
+	switch {
+	case err == errOffline:
+		parts = append(parts, "offline")
+	case err == errThrottled:
+		parts = append(parts, "slowed down")
+	case err == errRateLimited:
+		parts = append(parts, "rate limited")
+	default:
+		if age, err := cache().Age(q.cacheKey()); err == nil && age > time.Second*5 {
+			parts = append(parts, "cached "+relativeTime(time.Now().Add(-age)))
+		} else {
+			parts = append(parts, "just fetched")
+		}
+	}
+	return new(aw.Item).
+		Title(strings.Join(parts, " · ")).
+		Subtitle("↩ to search again, skipping the cache").
+		Arg(raw).
+		Icon(aw.IconSync).
+		Valid(true).
+		Var("action", "refresh-search")
+}
+
+// refreshSearch drops the cached results for raw and puts it back into
+// alfred through the workflow's external trigger, which searches afresh.
+func refreshSearch(ctx context.Context, raw string) error {
+	if err := cache().Store(parseQuery(raw).cacheKey(), nil); err != nil {
+		return err
+	}
+	return searchAgain(ctx, raw)
+}
+
+// searchAgain reopens alfred with raw typed into the workflow's search.
+func searchAgain(ctx context.Context, raw string) error {
+	out, err := exec.CommandContext(
+		ctx,
+		"osascript",
+		"-e", "on run argv",
+		"-e", `tell application id "com.runningwithcrayons.Alfred" to run trigger "search" in workflow (item 1 of argv) with argument (item 2 of argv)`,
+		"-e", "end run",
+		bundleID,
+		raw,
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to search again: %s: %s", err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const historyFile = "history.json"
+
+type historyEntry struct {
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+func loadHistory() map[string]historyEntry {
+	h := map[string]historyEntry{}
+	c := aw.NewCache(dataDir())
+	if c.Exists(historyFile) {
+		if err := c.LoadJSON(historyFile, &h); err != nil {
+			debug("failed to load search history: %s", err.Error())
+		}
+	}
+	return h
+}
+
+// recordQuery counts a search that produced results. alfred runs the script
+// filter on every keystroke so this also sees partial queries, topQueries
+// filters those back out.
+func recordQuery(term string) {
+	term = strings.ToLower(strings.TrimSpace(term))
+	if len(term) < 3 || private() {
+		return
+	}
+	h := loadHistory()
+	e := h[term]
+	e.Count++
+	e.Last = time.Now()
+	h[term] = e
+	if err := aw.NewCache(dataDir()).StoreJSON(historyFile, h); err != nil {
+		debug("failed to store search history: %s", err.Error())
+	}
+}
+
+// topQueries returns up to n of the most searched terms, skipping any term
+// that is just a prefix of another one (i.e. something typed on the way to a
+// longer query).
+func topQueries(n int) []string {
+	h := loadHistory()
+	var terms []string
+	for term := range h {
+		partial := false
+		for other := range h {
+			if other != term && strings.HasPrefix(other, term) {
+				partial = true
+				break
+			}
+		}
+		if !partial {
+			terms = append(terms, term)
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		a, b := h[terms[i]], h[terms[j]]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Last.After(b.Last)
+	})
+	if len(terms) > n {
+		terms = terms[:n]
+	}
+	return terms
+}
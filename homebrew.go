@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+// homebrew's cask api is a catalog of mac apps distributed outside the app
+// store, with download links. the whole list is fetched and searched
+// locally, it changes slowly.
+const (
+	caskAPIURL  = "https://formulae.brew.sh/api/cask.json"
+	brewLogoURL = "https://brew.sh/assets/img/homebrew-256x256.png"
+	casksFile   = "casks.json"
+	casksMaxAge = time.Hour * 24
+)
+
+type cask struct {
+	Token    string   `json:"token"`
+	Names    []string `json:"name"`
+	Desc     string   `json:"desc"`
+	Homepage string   `json:"homepage"`
+	URL      string   `json:"url"`
+	Version  string   `json:"version"`
+}
+
+func init() {
+	registerProvider(homebrew{})
+	registerRefreshJob("homebrew", func(ctx context.Context) error {
+		if !providerEnabled("homebrew") {
+			return nil
+		}
+		casks, err := fetchCasks(ctx)
+		if err != nil {
+			return err
+		}
+		return cache().StoreJSON(casksFile, casks)
+	})
+}
+
+type homebrew struct{}
+
+func (homebrew) name() string  { return "homebrew" }
+func (homebrew) label() string { return "Homebrew" }
+
+func (homebrew) search(ctx context.Context, q query) ([]app, error) {
+	var casks []cask
+	err := cache().LoadOrStoreJSON(casksFile, casksMaxAge, func() (interface{}, error) {
+		return fetchCasks(ctx)
+	}, &casks)
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(q.term())
+	limit := q.limit()
+	var results []app
+	for _, c := range casks {
+		if len(words) > 0 && containsAllWords(c.Token+" "+strings.Join(c.Names, " "), words) {
+			results = append(results, c.toApp())
+		}
+		if len(results) >= limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (c cask) toApp() app {
+	name := c.Token
+	if len(c.Names) > 0 {
+		name = c.Names[0]
+	}
+	version := c.Version
+	if version == "latest" {
+		version = ""
+	}
+	return app{
+		ID:          catalogID("homebrew", c.Token),
+		Name:        name,
+		URL:         c.Homepage,
+		Version:     version,
+		Summary:     c.Desc,
+		Artwork:     brewLogoURL,
+		DownloadURL: c.URL,
+		Install:     "brew install --cask " + c.Token,
+		Source:      "homebrew",
+	}
+}
+
+// modifiers: ⌘ downloads the app directly, ⌥ copies the brew command that
+// installs it.
+func (homebrew) modifiers(item *aw.Item, res app) {
+	if res.DownloadURL != "" {
+		item.NewModifier(aw.ModCmd).
+			Valid(true).
+			Arg(res.DownloadURL).
+			Subtitle("Download "+res.Name).
+			Var("action", "open")
+	}
+	item.NewModifier(aw.ModAlt).
+		Valid(true).
+		Arg(res.Install).
+		Subtitle("Copy “"+res.Install+"”").
+		Var("action", "copy")
+}
+
+func fetchCasks(ctx context.Context) ([]cask, error) {
+	req, err := http.NewRequest("GET", caskAPIURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	debug("sending request: %s %s", req.Method, req.URL.String())
+	// the list is several megabytes, more than client's timeout allows for
+	slow := &http.Client{Transport: client.Transport, Timeout: time.Minute}
+	resp, err := slow.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	var casks []cask
+	return casks, json.NewDecoder(resp.Body).Decode(&casks)
+}
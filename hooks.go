@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// hookTimeout stops a hung hook script from keeping the workflow around.
+const hookTimeout = time.Second * 30
+
+// runHook runs HOOK_SCRIPT, if set, after an action has been carried out.
+// the script gets the action, its arg and what is known about the app as
+// environment variables. a failing hook is logged, the action already
+// happened.
+func runHook(ctx context.Context, action, arg string) {
+	script := config("HOOK_SCRIPT")
+	if script == "" {
+		return
+	}
+	if strings.HasPrefix(script, "~/") {
+		script = filepath.Join(homeDir(), script[2:])
+	}
+	env := []string{
+		"ACTION=" + action,
+		"ARG=" + arg,
+		"APP_ID=" + os.Getenv("app_id"),
+		"APP_NAME=" + os.Getenv("app_name"),
+		"APP_DEVELOPER=" + os.Getenv("app_developer"),
+	}
+	if id, err := parseIDs([]string{os.Getenv("app_id")}); err == nil {
+		if res := indexedApp(id[0]); res != nil {
+			env = append(env,
+				"APP_BUNDLE_ID="+res.BundleID,
+				"APP_URL="+res.URL,
+				"APP_PRICE="+res.PriceFmt,
+				"APP_VERSION="+res.Version,
+				"APP_GENRE="+res.Genre,
+				fmt.Sprintf("APP_RATING=%.1f", res.Rating),
+			)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, script)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "hook %s failed: %s\n", script, err.Error())
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+// icons are stored by the sha-256 of their content, so the same artwork
+// served under several urls (sizes, cdn hosts) is only kept once. the index
+// maps each url we have downloaded to the blob holding its content.
+const (
+	iconDir       = "icons"
+	iconIndexFile = "index.json"
+	// maxIconSize guards against a misbehaving cdn filling the cache
+	maxIconSize = 8 << 20
+	// iconMaxAge is how long an icon is used before asking the cdn whether
+	// it changed, with a conditional get so an unchanged one costs a 304
+	iconMaxAge = time.Hour * 24 * 7
+)
+
+func init() {
+	register(command{
+		name:  "icons",
+		usage: "icons verify|stats",
+		desc:  "check the icon cache for corrupt files, or show its size",
+		run:   iconsCmd,
+	})
+}
+
+type iconCache struct {
+	dir string
+
+	mu    sync.Mutex
+	index map[string]iconEntry // keyed by url
+	dirty bool
+}
+
+type iconEntry struct {
+	File         string    `json:"file"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Checked      time.Time `json:"checked"`
+}
+
+func (e iconEntry) stale() bool {
+	return time.Since(e.Checked) > iconMaxAge
+}
+
+func openIconCache() *iconCache {
+	c := &iconCache{
+		dir:   filepath.Join(cacheDir(), iconDir),
+		index: map[string]iconEntry{},
+	}
+	data, err := ioutil.ReadFile(filepath.Join(c.dir, iconIndexFile))
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, &c.index); err != nil {
+		// indexes written before revalidation map urls straight to blobs.
+		// their icons count as stale, so they are revalidated on next use
+		var old map[string]string
+		if json.Unmarshal(data, &old) != nil {
+			debug("ignoring corrupt icon index: %s", err.Error())
+			return c
+		}
+		for url, name := range old {
+			c.index[url] = iconEntry{File: name}
+		}
+	}
+	return c
+}
+
+// path returns the blob for url, if it has been downloaded and is still on
+// disk.
+func (c *iconCache) path(url string) (string, bool) {
+	filename, _, ok := c.entry(url)
+	return filename, ok
+}
+
+// entry is path along with what is known about the blob.
+func (c *iconCache) entry(url string) (string, iconEntry, bool) {
+	c.mu.Lock()
+	e, ok := c.index[url]
+	if legacy, found := c.index[legacyKey(url)]; !ok && found {
+		e, ok = legacy, true
+		c.index[url] = e
+		delete(c.index, legacyKey(url))
+		c.dirty = true
+	}
+	c.mu.Unlock()
+	if !ok {
+		return "", e, false
+	}
+	filename := filepath.Join(c.dir, e.File)
+	if _, err := os.Stat(filename); err != nil {
+		return "", e, false
+	}
+	return filename, e, true
+}
+
+// revalidated records that the cdn says url has not changed.
+func (c *iconCache) revalidated(url string) {
+	c.mu.Lock()
+	e := c.index[url]
+	e.Checked = time.Now()
+	c.index[url] = e
+	c.dirty = true
+	c.mu.Unlock()
+}
+
+// adopt points url at the blob e names, when it is on disk.
+func (c *iconCache) adopt(url string, e iconEntry) (string, bool) {
+	filename := filepath.Join(c.dir, e.File)
+	if _, err := os.Stat(filename); e.File == "" || err != nil {
+		return "", false
+	}
+	c.mu.Lock()
+	c.index[url] = e
+	c.dirty = true
+	c.mu.Unlock()
+	return filename, true
+}
+
+// store writes data under its hash, unless an identical blob is already
+// there, and points url at it, remembering the validators in header. writes
+// go through a temp file so a crash never leaves a truncated icon behind.
+func (c *iconCache) store(url string, data []byte, contentType string, header http.Header) (string, error) {
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + iconExt(contentType, data)
+	filename := filepath.Join(c.dir, name)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+			return "", err
+		}
+		tmp, err := ioutil.TempFile(c.dir, ".download-")
+		if err != nil {
+			return "", err
+		}
+		_, err = tmp.Write(data)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), filename)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return "", err
+		}
+	} else {
+		debug("deduplicated %s into %s", url, name)
+	}
+	c.mu.Lock()
+	c.index[url] = iconEntry{
+		File:         name,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Checked:      time.Now(),
+	}
+	c.dirty = true
+	c.mu.Unlock()
+	return filename, nil
+}
+
+func (c *iconCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(c.dir, iconIndexFile), data, 0600); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// iconExt picks a file extension from the response content type, sniffing
+// the data when apple does not send a useful one.
+func iconExt(contentType string, data []byte) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mt == "application/octet-stream" {
+		mt, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	switch mt {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	}
+	return ".img"
+}
+
+// genericIcon stands in for artwork when ARTWORK is "none". alfred resolves
+// the relative path against the workflow folder, where package puts icon.png.
+var genericIcon = &aw.Icon{Value: "icon.png"}
+
+func downloadAllImages(ctx context.Context, concurrency int, urls []string) []*aw.Icon {
+	defer timed("icons")()
+	output := make([]*aw.Icon, len(urls))
+	if config("ARTWORK") == "none" {
+		for i := range output {
+			output[i] = genericIcon
+		}
+		return output
+	}
+	cache := openIconCache()
+	migrateLegacyIcons(cache)
+	var wg sync.WaitGroup
+	sem := make(chan bool, concurrency)
+	for i, u := range urls {
+		output[i] = aw.IconError
+		if u == "" {
+			output[i] = genericIcon
+			continue
+		}
+		filename, entry, ok := cache.entry(u)
+		if ok {
+			debug("file is cached (%s)", filename)
+			output[i] = &aw.Icon{Type: aw.IconTypeImage, Value: filename}
+			if !entry.stale() {
+				continue
+			}
+		}
+		wg.Add(1)
+		go func(i int, u string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			sem <- true
+			filename, err := fetchIcon(ctx, cache, u)
+			if err != nil && output[i] != aw.IconError {
+				debug("failed to revalidate %s, keeping the cached icon: %s", u, err.Error())
+				return
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "error: failed to download artwork: %s\n", err.Error())
+				return
+			}
+			output[i] = &aw.Icon{Type: aw.IconTypeImage, Value: filename}
+		}(i, u)
+	}
+	wg.Wait()
+	if err := cache.save(); err != nil {
+		debug("failed to save icon index: %s", err.Error())
+	}
+	return output
+}
+
+// downloadIcon fetches url into cache. when an earlier copy is cached the
+// request is conditional on it having changed.
+func downloadIcon(ctx context.Context, cache *iconCache, url string) (string, error) {
+	debug("downloading: %s", url)
+	req, err := http.NewRequest("GET", url, http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	cached, entry, ok := cache.entry(url)
+	if ok {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if ok && resp.StatusCode == http.StatusNotModified {
+		debug("not modified: %s", url)
+		cache.revalidated(url)
+		return cached, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxIconSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxIconSize {
+		return "", fmt.Errorf("artwork at %s is larger than %d bytes", url, maxIconSize)
+	}
+	data, contentType, err := renderableArtwork(ctx, data, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", fmt.Errorf("failed to convert artwork at %s: %s", url, err.Error())
+	}
+	data, contentType = shrinkIcon(data, contentType, configInt("ICON_SIZE"))
+	return cache.store(url, data, contentType, resp.Header)
+}
+
+func iconsCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: icons verify|stats")
+	}
+	switch args[0] {
+	case "verify":
+		return iconsVerify()
+	case "stats":
+		return iconsStats()
+	}
+	return fmt.Errorf("unknown icons command %q", args[0])
+}
+
+// iconsVerify rehashes every blob, deleting any whose content no longer
+// matches its name, and drops index entries pointing at missing blobs.
+func iconsVerify() error {
+	c := openIconCache()
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var corrupt int
+	for _, fi := range files {
+		name := fi.Name()
+		if name == iconIndexFile || fi.IsDir() {
+			continue
+		}
+		filename := filepath.Join(c.dir, name)
+		if strings.HasPrefix(name, ".") {
+			// interrupted downloads and download locks
+			os.Remove(filename)
+			continue
+		}
+		if strings.Contains(name, "-") {
+			// a badged copy, see badgedIcon
+			continue
+		}
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != strings.TrimSuffix(name, filepath.Ext(name)) {
+			fmt.Printf("corrupt: %s\n", name)
+			os.Remove(filename)
+			corrupt++
+		}
+	}
+	var dangling int
+	for url := range c.index {
+		if _, ok := c.path(url); !ok {
+			delete(c.index, url)
+			c.dirty = true
+			dangling++
+		}
+	}
+	fmt.Printf("%d corrupt icons removed, %d index entries dropped\n", corrupt, dangling)
+	return c.save()
+}
+
+func iconsStats() error {
+	c := openIconCache()
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var (
+		blobs int
+		size  int64
+	)
+	for _, fi := range files {
+		if fi.IsDir() || fi.Name() == iconIndexFile || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		blobs++
+		size += fi.Size()
+	}
+	fmt.Printf("%d urls, %d icons, %s on disk\n", len(c.index), blobs, humanSize(size))
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// the local index is a sqlite database of every app the workflow has ever
+// seen. it is driven through the sqlite3 binary that ships with macos rather
+// than a cgo driver, so the workflow stays a single static binary.
+const (
+	indexFile = "index.sqlite"
+
+	indexSchema = `CREATE TABLE IF NOT EXISTS apps (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	developer TEXT NOT NULL,
+	price TEXT NOT NULL,
+	rating REAL NOT NULL,
+	ratings INTEGER NOT NULL,
+	artwork TEXT NOT NULL,
+	data TEXT NOT NULL,
+	seen INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS apps_name ON apps (name);
+CREATE VIRTUAL TABLE IF NOT EXISTS descriptions USING fts5 (
+	id UNINDEXED,
+	name,
+	developer,
+	description,
+	tokenize = 'porter unicode61 remove_diacritics 2'
+);
+`
+
+	// ascii unit/record separators, what sqlite3's ".mode ascii" emits
+	sqlUnitSep   = "\x1f"
+	sqlRecordSep = "\x1e"
+)
+
+func sqlite(script string) ([][]string, error) {
+	filename, err := dataFile(indexFile)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteExec(filename, indexSchema+script)
+}
+
+// sqliteExec runs script against the database in filename and returns the
+// rows it printed.
+func sqliteExec(filename, script string) ([][]string, error) {
+	cmd := exec.Command("sqlite3", "-batch", "-bail", filename)
+	cmd.Stdin = strings.NewReader(".mode ascii\n" + script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sqlite3: %s: %s", err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	var rows [][]string
+	for _, rec := range strings.Split(string(out), sqlRecordSep) {
+		if rec == "" {
+			continue
+		}
+		rows = append(rows, strings.Split(rec, sqlUnitSep))
+	}
+	return rows, nil
+}
+
+func sqlQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// indexApps upserts the app store apps among results into the local index,
+// unless PRIVATE is on.
+func indexApps(results []app) {
+	if len(results) == 0 || private() {
+		return
+	}
+	var sql strings.Builder
+	sql.WriteString("BEGIN;\n")
+	now := time.Now().Unix()
+	for _, res := range results {
+		if res.Source != "" {
+			continue
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(
+			&sql,
+			"INSERT OR REPLACE INTO apps VALUES (%d, %s, %s, %s, %f, %d, %s, %s, %d);\n",
+			res.ID,
+			sqlQuote(res.Name),
+			sqlQuote(res.Developer),
+			sqlQuote(res.PriceFmt),
+			res.Rating,
+			res.NumRatings,
+			sqlQuote(res.Artwork),
+			sqlQuote(string(data)),
+			now,
+		)
+		if res.Description != "" {
+			fmt.Fprintf(
+				&sql,
+				"DELETE FROM descriptions WHERE id = %d;\nINSERT INTO descriptions VALUES (%d, %s, %s, %s);\n",
+				res.ID,
+				res.ID,
+				sqlQuote(res.Name),
+				sqlQuote(res.Developer),
+				sqlQuote(res.Description),
+			)
+		}
+	}
+	sql.WriteString("COMMIT;\n")
+	if _, err := sqlite(sql.String()); err != nil {
+		debug("failed to index results: %s", err.Error())
+	}
+}
+
+// searchIndex finds previously seen apps whose name or developer contains
+// every word of term.
+func searchIndex(term string, limit int) []app {
+	var where []string
+	for _, w := range strings.Fields(term) {
+		like := sqlQuote("%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(w) + "%")
+		where = append(where, fmt.Sprintf(`(name LIKE %s ESCAPE '\' OR developer LIKE %s ESCAPE '\')`, like, like))
+	}
+	if len(where) == 0 {
+		return nil
+	}
+	rows, err := sqlite(fmt.Sprintf(
+		"SELECT data FROM apps WHERE %s ORDER BY ratings DESC LIMIT %d;\n",
+		strings.Join(where, " AND "),
+		limit,
+	))
+	if err != nil {
+		debug("failed to search index: %s", err.Error())
+		return nil
+	}
+	results := make([]app, 0, len(rows))
+	for _, row := range rows {
+		var res app
+		if err := json.Unmarshal([]byte(row[0]), &res); err != nil {
+			debug("skipping corrupt index row: %s", err.Error())
+			continue
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// indexedApp returns the app with track id id from the local index, or nil.
+func indexedApp(id int64) *app {
+	rows, err := sqlite(fmt.Sprintf("SELECT data FROM apps WHERE id = %d;\n", id))
+	if err != nil {
+		debug("failed to read index: %s", err.Error())
+		return nil
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	var res app
+	if err := json.Unmarshal([]byte(rows[0][0]), &res); err != nil {
+		return nil
+	}
+	return &res
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	installedFile   = "installed.json"
+	installedMaxAge = time.Minute * 10
+)
+
+var (
+	installedOnce sync.Once
+	installedApps map[string]string // bundle id -> version
+)
+
+// installedVersion returns the version of the app with bundleID installed
+// on this mac, if there is one. spotlight is asked once for every app
+// bundle and the answer cached for a few minutes.
+func installedVersion(bundleID string) (string, bool) {
+	installedOnce.Do(func() {
+		installedApps = map[string]string{}
+		err := cache().LoadOrStoreJSON(installedFile, installedMaxAge, func() (interface{}, error) {
+			return findInstalledApps(context.Background())
+		}, &installedApps)
+		if err != nil {
+			debug("failed to find installed apps: %s", err.Error())
+		}
+	})
+	if bundleID == "" {
+		return "", false
+	}
+	v, ok := installedApps[bundleID]
+	return v, ok
+}
+
+func findInstalledApps(ctx context.Context) (map[string]string, error) {
+	out, err := exec.CommandContext(ctx, "mdfind", "-0", `kMDItemContentType == "com.apple.application-bundle"`).Output()
+	if err != nil {
+		return nil, err
+	}
+	paths := strings.Split(strings.TrimRight(string(out), "\x00"), "\x00")
+	apps := map[string]string{}
+	if len(paths) == 0 || paths[0] == "" {
+		return apps, nil
+	}
+	// one mdls for all of them, it prints each attribute NUL terminated
+	args := append([]string{"-raw", "-name", "kMDItemCFBundleIdentifier", "-name", "kMDItemVersion"}, paths...)
+	out, err = exec.CommandContext(ctx, "mdls", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	fields := bytes.Split(out, []byte{0})
+	for i := 0; i+1 < len(fields); i += 2 {
+		id, version := string(fields[i]), string(fields[i+1])
+		if id == "" || id == "(null)" {
+			continue
+		}
+		if version == "(null)" {
+			version = ""
+		}
+		apps[id] = version
+	}
+	return apps, nil
+}
+
+// versionLess compares dotted version numbers numerically: 1.9 < 1.10.
+func versionLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestVersionLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"1.9", "1.10", true},
+		{"1.10", "1.9", false},
+		{"1.2", "1.2", false},
+		{"1.2", "1.2.1", true},
+		{"1.2.0", "1.2", false},
+		{"2", "10", true},
+		{"", "1", true},
+	}
+	for _, tt := range tests {
+		if got := versionLess(tt.a, tt.b); got != tt.less {
+			t.Errorf("versionLess(%q, %q) = %t, want %t", tt.a, tt.b, got, tt.less)
+		}
+	}
+}
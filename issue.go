@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const (
+	issuesURL = "https://github.com/nkcmr/alfred-apple-app-search/issues/new"
+
+	// how much of the log goes into an issue. github refuses urls much
+	// longer than 8k, the body is cut down to fit below that.
+	issueLogLines = 40
+	issueMaxBody  = 6000
+)
+
+func init() {
+	registerAction(action{
+		name: "report-issue",
+		desc: "Report an issue on github",
+		run:  reportIssue,
+	})
+}
+
+// withReportIssue adds ⌥↩ to an error item, opening a new github issue about
+// problem.
+func withReportIssue(it *aw.Item, problem string) *aw.Item {
+	it.NewModifier(aw.ModAlt).
+		Valid(true).
+		Arg(problem).
+		Subtitle("Report an issue about this on github").
+		Var("action", "report-issue")
+	return it
+}
+
+// issueLog picks the log most likely to explain a problem: a recent crash,
+// or failing that the request trace, if either exists. it returns the file
+// name and its last issueLogLines lines.
+func issueLog() (string, string) {
+	candidates := []struct {
+		filename string
+		maxAge   time.Duration
+	}{
+		{filepath.Join(dataDir(), crashFile), crashNoticeWindow},
+		{filepath.Join(cacheDir(), traceFile), time.Hour},
+	}
+	for _, c := range candidates {
+		info, err := os.Stat(c.filename)
+		if err != nil || time.Since(info.ModTime()) > c.maxAge {
+			continue
+		}
+		data, err := ioutil.ReadFile(c.filename)
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		if len(lines) > issueLogLines {
+			lines = lines[len(lines)-issueLogLines:]
+		}
+		return filepath.Base(c.filename), strings.Join(lines, "\n")
+	}
+	return "", ""
+}
+
+// reportIssue opens a github issue about problem, prefilled with the workflow
+// and macos versions. the tail of the log is only added when the user agrees
+// to it, it can hold what they searched for.
+func reportIssue(ctx context.Context, problem string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**what happened**\n\n%s\n\n<!-- what were you doing when it happened? -->\n\n", problem)
+	fmt.Fprintf(&b, "**environment**\n\n- workflow: %s\n- macos: %s\n", versionString(), macOSVersion())
+	if name, tail := issueLog(); tail != "" {
+		include, err := askIncludeLog(ctx, name)
+		if err != nil {
+			return err
+		}
+		if include {
+			room := issueMaxBody - b.Len() - 64
+			if room < 0 {
+				room = 0
+			}
+			if len(tail) > room {
+				tail = tail[len(tail)-room:]
+			}
+			fmt.Fprintf(&b, "\n**%s**\n\n```\n%s\n```\n", name, tail)
+		}
+	}
+	u := issuesURL + "?" + url.Values{
+		"title": {problem},
+		"body":  {b.String()},
+	}.Encode()
+	return openURL(ctx, u)
+}
+
+// askIncludeLog asks whether the end of the log called name may go into the
+// issue.
+func askIncludeLog(ctx context.Context, name string) (bool, error) {
+	out, err := exec.CommandContext(
+		ctx,
+		"osascript",
+		"-e", "on run argv",
+		"-e", `display dialog "Include the last lines of " & (item 1 of argv) & " in the issue? They help track the problem down, but may show what you searched for. You can still edit the issue before submitting it." with title "Report an issue" buttons {"Don't Include", "Include"} default button "Include"`,
+		"-e", "button returned of result",
+		"-e", "end run",
+		name,
+	).CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("failed to ask about the log: %s: %s", err.Error(), strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)) == "Include", nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+// staleMark flags apps that have not been updated in a long time.
+const staleMark = "⚠︎"
+
+func storeURL(id int64) string {
+	return fmt.Sprintf("macappstores://itunes.apple.com/app/id%d", id)
+}
+
+func appItem(res app) *aw.Item {
+	if res.Source != "" {
+		return catalogItem(res)
+	}
+	item := new(aw.Item).
+		Title(res.Name).
+		Subtitle(subtitle(res)).
+		Arg(primaryURL(res)).
+		Autocomplete(fmt.Sprintf("id:%d", res.ID)).
+		Valid(true).
+		IsFile(false).
+		Var("app_id", fmt.Sprint(res.ID)).
+		Var("app_name", res.Name).
+		Var("app_developer", res.Developer)
+	addModifiers(item, res)
+	return item
+}
+
+// catalogItem is appItem for results from other providers. ↩ opens their
+// page, providers that can do more add their own modifiers.
+func catalogItem(res app) *aw.Item {
+	item := new(aw.Item).
+		Title(res.Name).
+		Subtitle(subtitle(res)).
+		Arg(res.URL).
+		Valid(true).
+		IsFile(false).
+		Var("app_name", res.Name).
+		Var("app_developer", res.Developer)
+	if m, ok := providers[res.Source].(catalogModifiers); ok {
+		m.modifiers(item, res)
+	}
+	return item
+}
+
+// subtitleFields are the pieces a result's subtitle can be built from, see
+// the SUBTITLE_FIELDS setting. a field returning "" is left out.
+var subtitleFields = map[string]func(res app) string{
+	"developer": func(res app) string {
+		if res.Seller != "" {
+			return res.Seller
+		}
+		return res.Developer
+	},
+	"genre": func(res app) string {
+		return res.Genre
+	},
+	"version": func(res app) string {
+		if res.Version == "" {
+			return ""
+		}
+		return "v" + res.Version
+	},
+	"size": func(res app) string {
+		if n := res.size(); n > 0 {
+			return humanSize(n)
+		}
+		return ""
+	},
+	"chart": func(res app) string {
+		return chartPosition(res.ID)
+	},
+	"foss": func(res app) string {
+		if alt, ok := fossAlternativeFor(res); ok {
+			return "FOSS alternative: " + alt.Name
+		}
+		return ""
+	},
+	"whatsnew": func(res app) string {
+		if notes := firstLine(res.ReleaseNotes); notes != "" {
+			return "new: " + truncate(notes, 60)
+		}
+		return ""
+	},
+	"summary": func(res app) string {
+		return truncate(firstLine(res.Description), 80)
+	},
+	"gamecenter": func(res app) string {
+		if res.isGame() && res.GameCenter {
+			return "Game Center"
+		}
+		return ""
+	},
+	"price": func(res app) string {
+		if home := homePrice(res); home != "" {
+			return res.PriceFmt + " (" + home + ")"
+		}
+		return res.PriceFmt
+	},
+	"rating": func(res app) string {
+		return fmt.Sprintf(
+			"%s(%s ratings)",
+			func() string {
+				glyph := ratingGlyph()
+				if res.Rating == float64(0) || glyph == "" {
+					return ""
+				}
+				return strings.Repeat(glyph, int(res.Rating)) + " "
+			}(),
+			ratingCount(res.NumRatings),
+		)
+	},
+	"age": func(res app) string {
+		return res.AgeRating
+	},
+	"updated": func(res app) string {
+		if res.Updated.IsZero() {
+			return ""
+		}
+		return "updated " + relativeTime(res.Updated)
+	},
+}
+
+// ratingCount formats a number of ratings per RATING_COUNT.
+func ratingCount(n int) string {
+	if config("RATING_COUNT") == "exact" {
+		return groupDigits(int64(n))
+	}
+	return compactCount(int64(n))
+}
+
+// ratingGlyph is the character ratings are drawn with, "" when the user
+// turned stars off.
+func ratingGlyph() string {
+	if g := config("RATING_GLYPH"); g != "none" {
+		return g
+	}
+	return ""
+}
+
+// subtitleFieldNames lists subtitleFields for the setting's description and
+// validation, which cannot refer to the map itself without an init cycle.
+var subtitleFieldNames = []string{
+	"developer", "price", "rating", "age", "updated", "genre", "version", "size", "gamecenter", "chart", "foss",
+	"whatsnew", "summary",
+}
+
+// spokenFields replace the subtitleFields that lean on glyphs or shorthand
+// when SUBTITLE_STYLE is "spoken", so voiceover reads them as sentences.
+var spokenFields = map[string]func(res app) string{
+	"rating": func(res app) string {
+		ratings := groupDigits(int64(res.NumRatings)) + " ratings"
+		if res.NumRatings == 1 {
+			ratings = "1 rating"
+		}
+		if res.Rating == float64(0) {
+			return ratings
+		}
+		return strconv.FormatFloat(res.Rating, 'f', -1, 64) + " stars, " + ratings
+	},
+	"version": func(res app) string {
+		if res.Version == "" {
+			return ""
+		}
+		return "version " + res.Version
+	},
+	"age": func(res app) string {
+		if res.AgeRating == "" {
+			return ""
+		}
+		return "rated " + res.AgeRating
+	},
+	"chart": func(res app) string {
+		pos := chartPosition(res.ID)
+		if pos == "" {
+			return ""
+		}
+		return "number " + strings.Replace(strings.TrimPrefix(pos, "#"), " ", " in ", 1)
+	},
+}
+
+func subtitle(res app) string {
+	spoken := config("SUBTITLE_STYLE") == "spoken"
+	var parts []string
+	if p, ok := providers[res.Source]; ok && res.Source != "" {
+		// other catalogs know too little for SUBTITLE_FIELDS to make sense
+		for _, v := range []string{p.label(), res.PriceFmt, subtitleFields["version"](res), res.Summary} {
+			if v != "" {
+				parts = append(parts, v)
+			}
+		}
+	} else {
+		for _, name := range strings.Split(config("SUBTITLE_FIELDS"), ",") {
+			field := subtitleFields[strings.TrimSpace(name)]
+			if f, ok := spokenFields[strings.TrimSpace(name)]; ok && spoken {
+				field = f
+			}
+			if v := field(res); v != "" {
+				parts = append(parts, v)
+			}
+		}
+	}
+	if res.Source == "" && len(searchEntities()) > 1 {
+		parts = append([]string{platformLabel(res)}, parts...)
+	}
+	if res.Source == "" && onSetapp(res) {
+		parts = append(parts, "on Setapp")
+	}
+	if len(res.Countries) > 0 {
+		if spoken {
+			parts = append(parts, "available in "+strings.ToUpper(strings.Join(res.Countries, ", ")))
+		} else {
+			parts = append(parts, strings.ToUpper(strings.Join(res.Countries, " ")))
+		}
+	}
+	if spoken {
+		s := strings.Join(parts, ", ")
+		if res.stale() {
+			s = "possibly abandoned, " + s
+		}
+		if isPinned(res.ID) {
+			s = "pinned, " + s
+		}
+		return s
+	}
+	s := strings.Join(parts, " | ")
+	if res.stale() {
+		s = staleMark + " " + s
+	}
+	if isPinned(res.ID) {
+		s = pinMark + " " + s
+	}
+	return s
+}
+
+func offlineItem(cached bool) *aw.Item {
+	subtitle := "no locally saved results match this search"
+	if cached {
+		subtitle = "showing locally saved results"
+	}
+	return withReportIssue(new(aw.Item).
+		Title("could not reach the app store").
+		Subtitle(subtitle).
+		Icon(aw.IconNetwork).
+		Valid(false), "could not reach the app store")
+}
+
+// partialItem says some of the results are missing because part of the
+// search failed.
+func partialItem(err error) *aw.Item {
+	return withReportIssue(new(aw.Item).
+		Title("some results could not be loaded").
+		Subtitle(err.Error()).
+		Icon(aw.IconWarning).
+		Valid(false), "search partly failed: "+err.Error())
+}
+
+func configErrorItem(err error) *aw.Item {
+	return withReportIssue(new(aw.Item).
+		Title("invalid workflow configuration").
+		Subtitle(err.Error()).
+		Icon(aw.IconWarning).
+		Valid(false), "invalid workflow configuration: "+err.Error())
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const itunesBaseURL = "https://itunes.apple.com/"
+
+// itunes calls one of the itunes api endpoints (search, lookup) and returns
+// the apps it found. it refuses to send anything while we are backing off
+// from a rate limit, returning errRateLimited, or when sending would go over
+// API_RATE_LIMIT, returning errThrottled.
+func itunes(ctx context.Context, endpoint string, params url.Values) ([]app, error) {
+	raw, err := itunesRaw(ctx, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+	defer timed("decode")()
+	results := make([]app, len(raw))
+	for i, r := range raw {
+		if err := json.Unmarshal(r, &results[i]); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// itunesRaw is itunes without decoding the results, for when every field
+// apple sends is wanted.
+func itunesRaw(ctx context.Context, endpoint string, params url.Values) ([]json.RawMessage, error) {
+	body, err := itunesGet(ctx, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+	saveLastResponse(body)
+	done := timed("decode")
+	var results struct {
+		Results []json.RawMessage `json:"results"`
+	}
+	err = json.Unmarshal(body, &results)
+	done()
+	if err != nil {
+		return nil, err
+	}
+	debug("successfully downloaded results (%d results)", len(results.Results))
+	clearBackOff()
+	return results.Results, nil
+}
+
+// itunesGet sends the request itself and reads the whole response, once
+// the rate limits allow it.
+func itunesGet(ctx context.Context, endpoint string, params url.Values) ([]byte, error) {
+	if rateLimited() {
+		debug("rate limited, not sending request")
+		return nil, errRateLimited
+	}
+	if err := takeToken(ctx); err != nil {
+		return nil, err
+	}
+	defer timed("api")()
+	u := itunesBaseURL + endpoint
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequest("GET", u, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	debug("sending request: %s %s", req.Method, req.URL.String())
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if isRateLimitStatus(resp.StatusCode) {
+		backOff(resp)
+		return nil, errRateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// lookup fetches a single app by its track id.
+func lookup(ctx context.Context, id int64) (*app, error) {
+	results, err := lookupMany(ctx, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return &results[0], nil
+}
+
+// lookupMany fetches several apps in one request. apps that no longer exist
+// (or are not sold in the configured storefront) are missing from the result.
+func lookupMany(ctx context.Context, ids []int64) ([]app, error) {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = fmt.Sprint(id)
+	}
+	results, err := lookupBackend(ctx, config("COUNTRY"), strs)
+	if err == nil {
+		indexApps(results)
+	}
+	return results, err
+}
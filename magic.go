@@ -0,0 +1,52 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+// magicPrefix mirrors awgo's convention: typing "workflow:" into the script
+// filter lists the workflow's housekeeping items instead of searching.
+const magicPrefix = "workflow:"
+
+type magicAction struct {
+	keyword string
+	desc    string
+	items   func() []*aw.Item
+}
+
+var magicActions = map[string]magicAction{}
+
+func registerMagic(m magicAction) {
+	magicActions[m.keyword] = m
+}
+
+func isMagic(term string) bool {
+	return strings.HasPrefix(term, magicPrefix)
+}
+
+func magicFeedback(term string) *aw.Feedback {
+	fb := aw.NewFeedback()
+	kw := strings.TrimPrefix(term, magicPrefix)
+	if m, ok := magicActions[kw]; ok {
+		fb.Items = m.items()
+		return fb
+	}
+	keywords := make([]string, 0, len(magicActions))
+	for k := range magicActions {
+		if strings.HasPrefix(k, kw) {
+			keywords = append(keywords, k)
+		}
+	}
+	sort.Strings(keywords)
+	for _, k := range keywords {
+		fb.NewItem(magicActions[k].desc).
+			Subtitle(magicPrefix + k).
+			Autocomplete(magicPrefix + k).
+			Icon(aw.IconSettings).
+			Valid(false)
+	}
+	return fb
+}
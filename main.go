@@ -4,28 +4,43 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
-	"path/filepath"
-	"runtime"
-	"strings"
-	"sync"
 	"syscall"
 	"time"
-
-	"github.com/deanishe/awgo"
 )
 
 const star rune = '⭑'
 
 var client = &http.Client{
-	Timeout: time.Second * 5,
+	Timeout:   time.Second * 5,
+	Transport: userAgentTransport{},
+}
+
+// userAgentTransport stamps every outgoing request with a user agent that
+// identifies the workflow. the USER_AGENT workflow variable overrides it.
+type userAgentTransport struct{}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", userAgent())
+	}
+	if tracing() {
+		return traceRoundTrip(baseTransport(), req)
+	}
+	return baseTransport().RoundTrip(req)
+}
+
+func userAgent() string {
+	if ua := config("USER_AGENT"); ua != "" {
+		return ua
+	}
+	return fmt.Sprintf("%s/%s (+https://github.com/nkcmr/alfred-apple-app-search)", binaryName, version)
 }
 
 func debug(format string, a ...interface{}) {
@@ -41,94 +56,17 @@ func md5hash(s string) string {
 	return hex.EncodeToString(sum)
 }
 
-// false -> new file
-// true  -> already exists
-func openFileIfNotExists(filename string) (*os.File, bool, error) {
-	_, err := os.Stat(filename)
-	if os.IsNotExist(err) {
-		f, err := os.Create(filename)
-		return f, false, err
-	}
-	if err != nil {
-		return nil, false, err
-	}
-	return nil, true, err
-}
-
-func downloadAllImages(ctx context.Context, concurrency int, urls []string) []*aw.Icon {
-	die := func(format string, a ...interface{}) {
-		fmt.Fprintf(os.Stderr, "error: "+format+"\n", a...)
-		// yes, deferred function calls will run even if Goexit() is called
-		// (https://play.golang.org/p/LZ5Mt6F1DQW) DONT CALL IN MAIN GO ROUTINE
-		runtime.Goexit()
-	}
-	output := make([]*aw.Icon, len(urls))
-	var wg sync.WaitGroup
-	sem := make(chan bool, concurrency)
-	dl := func(i int, url string) {
-		output[i] = aw.IconError
-		filename := fmt.Sprintf(
-			"%s/net.nkcmr.alfred-apple-app-search/%s.png",
-			strings.TrimRight(os.TempDir(), "/"),
-			md5hash(url),
-		)
-		if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
-			die(err.Error())
-			return
-		}
-		f, x, err := openFileIfNotExists(filename)
-		if err != nil {
-			die(
-				"failed to create or open file for downloaded artwork: %s",
-				err.Error(),
-			)
-			return
-		}
-		defer func() {
-			if f != nil {
-				defer f.Close()
-			}
-		}()
-		if !x {
-			debug("downloading: %s to %s", url, filename)
-			resp, err := client.Get(url)
-			if err != nil {
-				die("failed to request artwork: %s", err.Error())
-				return
-			}
-			defer resp.Body.Close()
-			if _, err := io.Copy(f, resp.Body); err != nil {
-				die("failed to download artwork: %s", err.Error())
-				return
-			}
-		} else {
-			debug("file is cached (%s)", filename)
-		}
-		output[i] = &aw.Icon{
-			Type:  aw.IconTypeImage,
-			Value: filename,
-		}
-	}
-	for i, u := range urls {
-		wg.Add(1)
-		go func(i int, u string) {
-			defer func() {
-				<-sem
-				wg.Done()
-			}()
-			sem <- true
-			dl(i, u)
-		}(i, u)
-	}
-	wg.Wait()
-	return output
+// downloadConcurrency is deliberately not tied to the cpu count, icon
+// downloads spend nearly all of their time waiting on the network.
+func downloadConcurrency() int {
+	return configInt("DOWNLOAD_CONCURRENCY")
 }
 
 func sigContext() context.Context {
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		defer cancel()
-		c := make(chan os.Signal)
+		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGTERM, syscall.SIGINT)
 		fmt.Printf("signal: %s\n", <-c)
 	}()
@@ -139,75 +77,28 @@ func main() {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("fatal error: %+v", r)
+			if filename, err := writeCrash(r); err != nil {
+				log.Printf("failed to write crash log: %s", err.Error())
+			} else {
+				log.Printf("crash log written to %s", filename)
+			}
+			if err := reportCrash(r); err != nil {
+				log.Printf("failed to send crash report: %s", err.Error())
+			}
 			os.Exit(1)
 		}
 	}()
 	ctx := sigContext()
-	url, err := url.ParseRequestURI(
-		"https://itunes.apple.com/search?media=software&entity=macSoftware&limit=20",
-	)
-	if err != nil {
-		panic(err)
-	}
-	q := url.Query()
-	q.Set("term", os.Args[1])
-	url.RawQuery = q.Encode()
-	req, err := http.NewRequest("GET", url.String(), http.NoBody)
-	if err != nil {
-		panic(err)
-	}
-	debug("sending request: %s %s", req.Method, req.URL.String())
-	resp, err := client.Do(req.WithContext(ctx))
-	if err != nil {
-		panic(err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		panic(fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode))
-	}
-	var results struct {
-		Results []struct {
-			ID         int64   `json:"trackId"`
-			Name       string  `json:"trackName"`
-			Artwork    string  `json:"artworkUrl512"`
-			URL        string  `json:"trackViewUrl"`
-			Rating     float64 `json:"averageUserRating"`
-			PriceFmt   string  `json:"formattedPrice"`
-			NumRatings int     `json:"userRatingCount"`
-		} `json:"results"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		panic(err)
-	}
-	debug("successfully downloaded results (%d results)", len(results.Results))
-	images := make([]string, len(results.Results))
-	fb := aw.NewFeedback()
-	for i, res := range results.Results {
-		item := new(aw.Item).
-			Title(res.Name).
-			Subtitle(
-				fmt.Sprintf(
-					"%s | %s(%d ratings)",
-					res.PriceFmt,
-					func() string {
-						if res.Rating == float64(0) {
-							return ""
-						}
-						return strings.Repeat(string(star), int(res.Rating)) + " "
-					}(),
-					res.NumRatings,
-				),
-			).
-			Arg(fmt.Sprintf("macappstores://itunes.apple.com/app/id%d", res.ID)).
-			Valid(true).
-			IsFile(false)
-		item.NewModifier(aw.ModAlt).Arg(res.URL).Valid(true).Subtitle("Open in browser")
-		fb.Items = append(fb.Items, item)
-		images[i] = res.Artwork
-	}
-	icons := downloadAllImages(ctx, runtime.NumCPU(), images)
-	for i := range icons {
-		fb.Items[i] = fb.Items[i].Icon(icons[i])
+	cmd, args := lookupCommand(os.Args[1:])
+	// a returned error is an expected failure (bad arguments, a request
+	// that failed) and not a crash, only panics end up in the crash log.
+	// both are reported though
+	if err := cmd.run(ctx, args); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", binaryName, err.Error())
+		if err := reportError(err); err != nil {
+			log.Printf("failed to send crash report: %s", err.Error())
+		}
+		os.Exit(1)
 	}
-	json.NewEncoder(os.Stdout).Encode(fb)
+	logTimings()
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// legacyIconDir is where artwork was downloaded before the workflow cache
+// dir was used, named by the md5 of its url.
+func legacyIconDir() string {
+	return filepath.Join(os.TempDir(), bundleID)
+}
+
+// legacyKey is the index key for artwork migrated from legacyIconDir. the
+// urls are long gone by then, only their hash survives, so entry looks
+// urls up under it when they are not in the index themselves.
+func legacyKey(url string) string {
+	return legacyKeyPrefix + md5hash(url)
+}
+
+const legacyKeyPrefix = "legacy:"
+
+// migrateLegacyIcons moves artwork from legacyIconDir into c and removes
+// the old dir, so upgrading does not mean downloading every icon again.
+func migrateLegacyIcons(c *iconCache) {
+	dir := legacyIconDir()
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var moved int
+	for _, fi := range files {
+		name := fi.Name()
+		if fi.IsDir() || filepath.Ext(name) != ".png" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		data, contentType := shrinkIcon(data, "image/png", configInt("ICON_SIZE"))
+		if _, err := c.store(legacyKeyPrefix+strings.TrimSuffix(name, ".png"), data, contentType, nil); err != nil {
+			debug("failed to migrate %s: %s", name, err.Error())
+			return
+		}
+		moved++
+	}
+	if err := c.save(); err != nil {
+		debug("failed to save icon index after migration: %s", err.Error())
+		return
+	}
+	debug("migrated %d icons from %s", moved, dir)
+	if err := os.RemoveAll(dir); err != nil {
+		debug("failed to remove %s: %s", dir, err.Error())
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizeQuery puts a raw query into one canonical form before it is
+// parsed, sent to apple or used as a cache key, so "Café" and "cafe " are
+// the same search: unicode nfc, case folded, whitespace trimmed and
+// collapsed. STRIP_DIACRITICS additionally turns "é" into "e".
+func normalizeQuery(raw string) string {
+	s := norm.NFC.String(raw)
+	if config("STRIP_DIACRITICS") == "yes" {
+		s = stripDiacritics(s)
+	}
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// containsAllWords reports whether text, normalized, contains every one of
+// words. it is how local lists (catalogs, pins, recently viewed) are
+// matched against a query.
+func containsAllWords(text string, words []string) bool {
+	text = normalizeQuery(text)
+	for _, w := range words {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripDiacritics decomposes s and drops the combining marks.
+func stripDiacritics(s string) string {
+	return norm.NFC.String(strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, norm.NFD.String(s)))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// notification events. each can be switched off with the NOTIFY setting.
+const (
+	eventPriceDrop = "price-drop"
+	eventUpdate    = "update"
+	eventDigest    = "digest"
+)
+
+var notifyEvents = []string{eventPriceDrop, eventUpdate, eventDigest}
+
+func init() {
+	register(command{
+		name:  "notify",
+		usage: "notify <event> <title> <message> [url]",
+		desc:  "show a macos notification, if notifications for event are enabled",
+		run: func(ctx context.Context, args []string) error {
+			if len(args) < 3 {
+				return fmt.Errorf("usage: notify <event> <title> <message> [url]")
+			}
+			var url string
+			if len(args) > 3 {
+				url = args[3]
+			}
+			return notify(ctx, args[0], args[1], args[2], url)
+		},
+	})
+}
+
+func notifyEnabled(event string) bool {
+	for _, e := range strings.Split(config("NOTIFY"), ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// notify posts a notification for event. terminal-notifier is used when it is
+// installed, since it can open url when the notification is clicked; otherwise
+// osascript, which cannot.
+func notify(ctx context.Context, event, title, message, url string) error {
+	if !notifyEnabled(event) {
+		debug("notifications for %s are disabled", event)
+		return nil
+	}
+	return postNotification(ctx, event, title, message, url)
+}
+
+// postNotification shows a notification whatever NOTIFY says, for the
+// result of something the user asked for. notifications in the same group
+// replace each other.
+func postNotification(ctx context.Context, group, title, message, url string) error {
+	var cmd *exec.Cmd
+	if tn, err := exec.LookPath("terminal-notifier"); err == nil {
+		args := []string{"-title", title, "-message", message, "-group", bundleID + "." + group}
+		if url != "" {
+			args = append(args, "-open", url)
+		}
+		cmd = exec.CommandContext(ctx, tn, args...)
+	} else {
+		cmd = exec.CommandContext(
+			ctx,
+			"osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title,
+			message,
+		)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to post notification: %s: %s", err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+// setupDoneFile is left in the data dir once the first run setup has been
+// finished or skipped, so it is only offered once.
+const setupDoneFile = "setup-done"
+
+// setupStep is one question of the first run setup, answered by picking one
+// of its choices for setting.
+type setupStep struct {
+	name    string
+	setting string
+	title   string
+	choices func() []string
+}
+
+var setupSteps = []setupStep{
+	{
+		name:    "country",
+		setting: "COUNTRY",
+		title:   "Country",
+		choices: func() []string { return storefrontCodes },
+	},
+	{
+		name:    "limit",
+		setting: "RESULT_LIMIT",
+		title:   "Result limit",
+		choices: func() []string { return []string{"10", "20", "50", "100", "200"} },
+	},
+	{
+		name:    "icons",
+		setting: "ICON_SIZE",
+		title:   "Icon size",
+		choices: func() []string { return []string{"64", "128", "256", "512", "0"} },
+	},
+}
+
+func init() {
+	registerAction(action{
+		name: "set-config",
+		desc: "Change a setting",
+		run:  setConfig,
+	})
+	registerAction(action{
+		name: "finish-setup",
+		desc: "Finish first run setup",
+		run:  finishSetup,
+	})
+}
+
+// firstRun reports whether the setup should be offered: it has never been
+// finished or skipped, and none of the settings it asks about have been
+// changed by hand. alfred hands us every default as an environment variable,
+// so an untouched configuration looks exactly like a missing one.
+func firstRun() bool {
+	if fileExists(filepath.Join(dataDir(), setupDoneFile)) {
+		return false
+	}
+	for _, step := range setupSteps {
+		if v := strings.TrimSpace(os.Getenv(step.setting)); v != "" && v != lookupSetting(step.setting).def {
+			return false
+		}
+	}
+	return true
+}
+
+// welcomeItem starts the first run setup. ⌘↩ keeps the defaults instead.
+func welcomeItem() *aw.Item {
+	it := new(aw.Item).
+		Title("Welcome! Set up App Store search").
+		Subtitle("↩ to pick your country, result limit and icon size, ⌘↩ to keep the defaults").
+		Autocomplete("setup:").
+		Icon(aw.IconSettings).
+		Valid(false)
+	it.NewModifier(aw.ModCmd).
+		Valid(true).
+		Arg("done").
+		Subtitle("Keep the defaults ("+strings.ToUpper(config("COUNTRY"))+" store)").
+		Var("action", "finish-setup")
+	return it
+}
+
+// setupFeedback lists the setup steps with their current values, or with a
+// step named the choices for it narrowed down by filter.
+func setupFeedback(fb *aw.Feedback, name, filter string) {
+	for _, step := range setupSteps {
+		if step.name == name {
+			setupChoices(fb, step, filter)
+			return
+		}
+	}
+	for i, step := range setupSteps {
+		fb.NewItem(fmt.Sprintf("%d. %s: %s", i+1, step.title, setupValue(step, config(step.setting)))).
+			Subtitle(lookupSetting(step.setting).desc).
+			Autocomplete("setup:" + step.name + " ").
+			Icon(aw.IconSettings).
+			Valid(false)
+	}
+	fb.NewItem("Done").
+		Subtitle("start searching, everything else is in the workflow's configuration").
+		Arg("done").
+		Icon(aw.IconInfo).
+		Valid(true).
+		Var("action", "finish-setup")
+}
+
+func setupChoices(fb *aw.Feedback, step setupStep, filter string) {
+	current := config(step.setting)
+	n := 0
+	for _, choice := range step.choices() {
+		if !strings.HasPrefix(choice, filter) {
+			continue
+		}
+		sub := "↩ to use this"
+		if choice == current {
+			sub = "current setting"
+		}
+		n++
+		fb.NewItem(setupValue(step, choice)).
+			Subtitle(sub).
+			Arg(step.setting+"="+choice).
+			Icon(aw.IconSettings).
+			Valid(true).
+			Var("action", "set-config")
+	}
+	if n == 0 {
+		fb.NewItem("No matching " + strings.ToLower(step.title)).
+			Subtitle("setup:" + step.name + " lists every choice").
+			Autocomplete("setup:" + step.name + " ").
+			Icon(aw.IconWarning).
+			Valid(false)
+	}
+}
+
+func setupValue(step setupStep, v string) string {
+	switch step.setting {
+	case "COUNTRY":
+		return strings.ToUpper(v)
+	case "ICON_SIZE":
+		if v == "0" {
+			return "as downloaded"
+		}
+		return v + "px"
+	}
+	return v
+}
+
+// setConfig saves KEY=value to the workflow's configuration through alfred,
+// which hands it to every run from then on, and goes back to the setup.
+func setConfig(ctx context.Context, arg string) error {
+	i := strings.IndexByte(arg, '=')
+	if i <= 0 {
+		return fmt.Errorf("expected KEY=value, got %q", arg)
+	}
+	key, value := arg[:i], arg[i+1:]
+	var s setting
+	for _, step := range setupSteps {
+		if step.setting == key {
+			s = lookupSetting(key)
+		}
+	}
+	if s.key == "" {
+		return fmt.Errorf("%s cannot be changed from alfred", key)
+	}
+	if s.check != nil {
+		if err := s.check(value); err != nil {
+			return fmt.Errorf("%s: %s", s.label, err.Error())
+		}
+	}
+	out, err := exec.CommandContext(
+		ctx,
+		"osascript",
+		"-e", "on run argv",
+		"-e", `tell application id "com.runningwithcrayons.Alfred" to set configuration (item 1 of argv) to value (item 2 of argv) in workflow (item 3 of argv) exportable false`,
+		"-e", "end run",
+		key,
+		value,
+		bundleID,
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %s: %s", s.label, err.Error(), strings.TrimSpace(string(out)))
+	}
+	if firstRun() {
+		return searchAgain(ctx, "setup:")
+	}
+	return nil
+}
+
+func finishSetup(ctx context.Context, _ string) error {
+	return ioutil.WriteFile(filepath.Join(dataDir(), setupDoneFile), []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+func init() {
+	register(command{
+		name:  "open",
+		usage: "open <arg>",
+		desc:  "run the action picked in alfred ($action, default open) on arg",
+		run:   openCmd,
+	})
+}
+
+// openCmd is what alfred runs when a result is actioned. the script filter
+// hands over the app and the action to run via item variables, which arrive
+// here as environment variables.
+func openCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "" {
+		return fmt.Errorf("arg is required")
+	}
+	name := os.Getenv("action")
+	if name == "" {
+		name = "open"
+	}
+	a, ok := actions[name]
+	if !ok {
+		return fmt.Errorf("unknown action %q", name)
+	}
+	if id := os.Getenv("app_id"); id != "" {
+		recordAction(id, os.Getenv("app_developer"))
+	}
+	if err := a.run(ctx, args[0]); err != nil {
+		return err
+	}
+	if id := os.Getenv("app_id"); id != "" && viewActions[name] {
+		recordViewedID(ctx, id)
+	}
+	runHook(ctx, name, args[0])
+	return nil
+}
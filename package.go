@@ -0,0 +1,450 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"text/template"
+)
+
+const (
+	bundleID   = "net.nkcmr.alfred-apple-app-search"
+	binaryName = "alfred-apple-app-search"
+
+	// used when the repo has no icon.png of its own
+	appStoreIcon = "/System/Applications/App Store.app/Contents/Resources/AppIcon.icns"
+)
+
+var infoPlist = template.Must(template.New("info.plist").Funcs(template.FuncMap{
+	"xml": template.HTMLEscapeString,
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>bundleid</key>
+	<string>{{.BundleID | xml}}</string>
+	<key>category</key>
+	<string>Internet</string>
+	<key>connections</key>
+	<dict>
+		<key>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E01</key>
+		<array>
+			<dict>
+				<key>destinationuid</key>
+				<string>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E02</string>
+				<key>modifiers</key>
+				<integer>0</integer>
+				<key>modifiersubtext</key>
+				<string></string>
+				<key>vitoclose</key>
+				<false/>
+			</dict>
+		</array>
+		<key>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E04</key>
+		<array>
+			<dict>
+				<key>destinationuid</key>
+				<string>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E02</string>
+				<key>modifiers</key>
+				<integer>0</integer>
+				<key>modifiersubtext</key>
+				<string></string>
+				<key>vitoclose</key>
+				<false/>
+			</dict>
+		</array>
+		<key>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E03</key>
+		<array>
+			<dict>
+				<key>destinationuid</key>
+				<string>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E01</string>
+				<key>modifiers</key>
+				<integer>0</integer>
+				<key>modifiersubtext</key>
+				<string></string>
+				<key>vitoclose</key>
+				<false/>
+			</dict>
+		</array>
+		<key>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E05</key>
+		<array>
+			<dict>
+				<key>destinationuid</key>
+				<string>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E06</string>
+				<key>modifiers</key>
+				<integer>0</integer>
+				<key>modifiersubtext</key>
+				<string></string>
+				<key>vitoclose</key>
+				<false/>
+			</dict>
+		</array>
+	</dict>
+	<key>createdby</key>
+	<string>nkcmr</string>
+	<key>description</key>
+	<string>search the mac app store within alfred</string>
+	<key>disabled</key>
+	<false/>
+	<key>name</key>
+	<string>Apple App Search</string>
+	<key>objects</key>
+	<array>
+		<dict>
+			<key>config</key>
+			<dict>
+				<key>alfredfiltersresults</key>
+				<false/>
+				<key>argumenttype</key>
+				<integer>1</integer>
+				<key>escaping</key>
+				<integer>102</integer>
+				<key>keyword</key>
+				<string>app</string>
+				<key>queuedelaycustom</key>
+				<integer>3</integer>
+				<key>queuedelayimmediatelyinitially</key>
+				<false/>
+				<key>queuedelaymode</key>
+				<integer>0</integer>
+				<key>queuemode</key>
+				<integer>2</integer>
+				<key>runningsubtext</key>
+				<string>searching the app store…</string>
+				<key>script</key>
+				<string>./{{.Binary}} search "$1"</string>
+				<key>scriptargtype</key>
+				<integer>1</integer>
+				<key>scriptfile</key>
+				<string></string>
+				<key>title</key>
+				<string>Search the Mac App Store</string>
+				<key>type</key>
+				<integer>0</integer>
+				<key>withspace</key>
+				<true/>
+			</dict>
+			<key>type</key>
+			<string>alfred.workflow.input.scriptfilter</string>
+			<key>uid</key>
+			<string>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E01</string>
+			<key>version</key>
+			<integer>2</integer>
+		</dict>
+		<dict>
+			<key>config</key>
+			<dict>
+				<key>concurrently</key>
+				<false/>
+				<key>escaping</key>
+				<integer>102</integer>
+				<key>script</key>
+				<string>./{{.Binary}} open "$1"</string>
+				<key>scriptargtype</key>
+				<integer>1</integer>
+				<key>scriptfile</key>
+				<string></string>
+				<key>type</key>
+				<integer>0</integer>
+			</dict>
+			<key>type</key>
+			<string>alfred.workflow.action.script</string>
+			<key>uid</key>
+			<string>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E02</string>
+			<key>version</key>
+			<integer>2</integer>
+		</dict>
+		<dict>
+			<key>config</key>
+			<dict>
+				<key>alfredfiltersresults</key>
+				<false/>
+				<key>argumenttype</key>
+				<integer>1</integer>
+				<key>escaping</key>
+				<integer>102</integer>
+				<key>keyword</key>
+				<string>apprecent</string>
+				<key>queuedelaycustom</key>
+				<integer>3</integer>
+				<key>queuedelayimmediatelyinitially</key>
+				<true/>
+				<key>queuedelaymode</key>
+				<integer>0</integer>
+				<key>queuemode</key>
+				<integer>1</integer>
+				<key>runningsubtext</key>
+				<string></string>
+				<key>script</key>
+				<string>./{{.Binary}} search "recent: $1"</string>
+				<key>scriptargtype</key>
+				<integer>1</integer>
+				<key>scriptfile</key>
+				<string></string>
+				<key>subtext</key>
+				<string>apps you recently looked at</string>
+				<key>title</key>
+				<string>Recently Viewed Apps</string>
+				<key>type</key>
+				<integer>0</integer>
+				<key>withspace</key>
+				<true/>
+			</dict>
+			<key>type</key>
+			<string>alfred.workflow.input.scriptfilter</string>
+			<key>uid</key>
+			<string>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E04</string>
+			<key>version</key>
+			<integer>2</integer>
+		</dict>
+		<dict>
+			<key>config</key>
+			<dict>
+				<key>availableviaurlhandler</key>
+				<true/>
+				<key>triggerid</key>
+				<string>search</string>
+			</dict>
+			<key>type</key>
+			<string>alfred.workflow.trigger.external</string>
+			<key>uid</key>
+			<string>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E03</string>
+			<key>version</key>
+			<integer>1</integer>
+		</dict>
+		<dict>
+			<key>config</key>
+			<dict>
+				<key>argumenttype</key>
+				<integer>0</integer>
+				<key>keyword</key>
+				<string>appstore</string>
+				<key>subtext</key>
+				<string>open the app store's search for it</string>
+				<key>text</key>
+				<string>Search the App Store for '{query}'</string>
+				<key>withspace</key>
+				<true/>
+			</dict>
+			<key>type</key>
+			<string>alfred.workflow.input.keyword</string>
+			<key>uid</key>
+			<string>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E05</string>
+			<key>version</key>
+			<integer>1</integer>
+		</dict>
+		<dict>
+			<key>config</key>
+			<dict>
+				<key>concurrently</key>
+				<false/>
+				<key>escaping</key>
+				<integer>102</integer>
+				<key>script</key>
+				<string>./{{.Binary}} --fallback "$1"</string>
+				<key>scriptargtype</key>
+				<integer>1</integer>
+				<key>scriptfile</key>
+				<string></string>
+				<key>type</key>
+				<integer>0</integer>
+			</dict>
+			<key>type</key>
+			<string>alfred.workflow.action.script</string>
+			<key>uid</key>
+			<string>9B2C7C43-2D5B-4E0B-9B57-7C1C4F3A1E06</string>
+			<key>version</key>
+			<integer>2</integer>
+		</dict>
+	</array>
+	<key>readme</key>
+	<string></string>
+	<key>uidata</key>
+	<dict/>
+	<key>userconfigurationconfig</key>
+	<array>{{range .Settings}}
+		<dict>
+			<key>config</key>
+			<dict>
+				<key>default</key>
+				<string>{{.Default | xml}}</string>
+				<key>placeholder</key>
+				<string></string>
+				<key>required</key>
+				<false/>
+				<key>trim</key>
+				<true/>
+			</dict>
+			<key>description</key>
+			<string>{{.Description | xml}}</string>
+			<key>label</key>
+			<string>{{.Label | xml}}</string>
+			<key>type</key>
+			<string>textfield</string>
+			<key>variable</key>
+			<string>{{.Key | xml}}</string>
+		</dict>{{end}}
+	</array>
+	<key>version</key>
+	<string>{{.Version | xml}}</string>
+	<key>webaddress</key>
+	<string>https://github.com/nkcmr/alfred-apple-app-search</string>
+</dict>
+</plist>
+`))
+
+func init() {
+	register(command{
+		name:  "package",
+		usage: "package [-o file] [-icon file] [-version v]",
+		desc:  "build a universal binary and bundle it into a .alfredworkflow",
+		run:   packageCmd,
+	})
+}
+
+func packageCmd(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("package", flag.ContinueOnError)
+	out := fs.String("o", binaryName+".alfredworkflow", "output file")
+	icon := fs.String("icon", "icon.png", "workflow icon (png)")
+	wfVersion := fs.String("version", "", "workflow version (defaults to git describe)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *wfVersion == "" {
+		*wfVersion = gitOutput(ctx, "describe", "--tags", "--always", "--dirty")
+	}
+	build, err := ioutil.TempDir("", binaryName)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(build)
+
+	bin := filepath.Join(build, binaryName)
+	if err := buildUniversal(ctx, bin, ldflags(ctx, *wfVersion)); err != nil {
+		return err
+	}
+	iconFile, err := workflowIcon(ctx, *icon, build)
+	if err != nil {
+		return err
+	}
+	plist := filepath.Join(build, "info.plist")
+	f, err := os.Create(plist)
+	if err != nil {
+		return err
+	}
+	type plistSetting struct {
+		Key, Label, Description, Default string
+	}
+	var ps []plistSetting
+	for _, s := range settings {
+		ps = append(ps, plistSetting{s.key, s.label, s.desc, s.def})
+	}
+	err = infoPlist.Execute(f, map[string]interface{}{
+		"BundleID": bundleID,
+		"Binary":   binaryName,
+		"Version":  *wfVersion,
+		"Settings": ps,
+	})
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to render info.plist: %s", err.Error())
+	}
+	return zipFiles(*out, map[string]string{
+		"info.plist": plist,
+		"icon.png":   iconFile,
+		binaryName:   bin,
+	})
+}
+
+// buildUniversal compiles the workflow for both apple silicon and intel and
+// glues the results together with lipo.
+func buildUniversal(ctx context.Context, out, ldflags string) error {
+	var slices []string
+	for _, arch := range []string{"arm64", "amd64"} {
+		slice := out + "-" + arch
+		debug("building %s", slice)
+		cmd := exec.CommandContext(ctx, "go", "build", "-trimpath", "-ldflags="+ldflags, "-o", slice, ".")
+		cmd.Env = append(os.Environ(), "GOOS=darwin", "GOARCH="+arch, "CGO_ENABLED=0")
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to build for %s: %s", arch, err.Error())
+		}
+		slices = append(slices, slice)
+	}
+	cmd := exec.CommandContext(ctx, "lipo", append([]string{"-create", "-output", out}, slices...)...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to create universal binary: %s", err.Error())
+	}
+	return nil
+}
+
+func workflowIcon(ctx context.Context, icon, dir string) (string, error) {
+	if _, err := os.Stat(icon); err == nil {
+		return icon, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	debug("%s not found, converting the app store icon", icon)
+	out := filepath.Join(dir, "icon.png")
+	cmd := exec.CommandContext(ctx, "sips", "-s", "format", "png", "-Z", "256", appStoreIcon, "--out", out)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to convert app store icon: %s", err.Error())
+	}
+	return out, nil
+}
+
+// zipFiles writes an archive to filename containing each source file under
+// the name it is keyed by.
+func zipFiles(filename string, files map[string]string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// sorted names and zeroed timestamps keep the archive byte-for-byte
+	// reproducible for a given set of inputs
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		if err := addZipFile(zw, name, files[name]); err != nil {
+			return fmt.Errorf("failed to add %s to archive: %s", name, err.Error())
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func addZipFile(zw *zip.Writer, name, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	hdr.SetMode(info.Mode())
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+// maxResultLimit is the most results the search api hands out for one
+// search. it has no offset, so page n asks for n pages' worth and shows the
+// last one.
+const maxResultLimit = 200
+
+// limit is how many results to ask for to fill q's page, RESULT_LIMIT being
+// the page size.
+func (q query) limit() int {
+	n := configInt("RESULT_LIMIT") * q.page
+	if n > maxResultLimit {
+		n = maxResultLimit
+	}
+	return n
+}
+
+// paginate cuts q's page out of results. full says the provider returned
+// as many results as were asked for, so there may be more beyond them.
+func (q query) paginate(results []app, full bool) ([]app, bool) {
+	size := configInt("RESULT_LIMIT")
+	start := (q.page - 1) * size
+	if start >= len(results) {
+		return nil, false
+	}
+	end := start + size
+	if end > len(results) {
+		end = len(results)
+	}
+	more := end < len(results) || (full && q.limit() < maxResultLimit)
+	return results[start:end], more
+}
+
+// pageItem autocompletes raw into page n of its results, labelled as the
+// page before or after the one shown.
+func pageItem(raw string, n int, label string) *aw.Item {
+	rest := strings.TrimSpace(withoutOperator(raw, "page"))
+	if n > 1 {
+		rest += fmt.Sprintf(" page:%d", n)
+	}
+	return new(aw.Item).
+		Title(fmt.Sprintf("%s (%d)", label, n)).
+		Subtitle(fmt.Sprintf("⇥ or ↩ to go to page %d", n)).
+		Autocomplete(rest + " ").
+		Icon(aw.IconInfo).
+		Valid(false)
+}
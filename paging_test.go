@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestPaginate(t *testing.T) {
+	setenv(t, "RESULT_LIMIT", "3")
+	results := make([]app, 7)
+	for i := range results {
+		results[i].ID = int64(i + 1)
+	}
+	tests := []struct {
+		page  int
+		full  bool
+		first int64
+		n     int
+		more  bool
+	}{
+		{1, false, 1, 3, true},
+		{2, false, 4, 3, true},
+		{3, false, 7, 1, false},
+		{3, true, 7, 1, true},
+		{4, false, 0, 0, false},
+	}
+	for _, tt := range tests {
+		q := query{page: tt.page}
+		got, more := q.paginate(results, tt.full)
+		var first int64
+		if len(got) > 0 {
+			first = got[0].ID
+		}
+		if len(got) != tt.n || first != tt.first || more != tt.more {
+			t.Errorf("page %d (full %t) = %d results from %d, more %t; want %d from %d, more %t", tt.page, tt.full, len(got), first, more, tt.n, tt.first, tt.more)
+		}
+	}
+}
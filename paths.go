@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// dataDir is where persistent state lives. alfred tells us where that is; when
+// run outside of alfred fall back to the same place alfred would have picked.
+func dataDir() string {
+	if d := os.Getenv("alfred_workflow_data"); d != "" {
+		return d
+	}
+	return filepath.Join(homeDir(), "Library/Application Support/Alfred/Workflow Data", bundleID)
+}
+
+// cacheDir is for anything that can be thrown away and re-fetched.
+func cacheDir() string {
+	if d := os.Getenv("alfred_workflow_cache"); d != "" {
+		return d
+	}
+	return filepath.Join(homeDir(), "Library/Caches/com.runningwithcrayons.Alfred/Workflow Data", bundleID)
+}
+
+func homeDir() string {
+	h, err := os.UserHomeDir()
+	if err != nil {
+		return os.TempDir()
+	}
+	return h
+}
+
+// dataFile returns the path to name inside the data dir, creating the dir if
+// needed.
+func dataFile(name string) (string, error) {
+	d := dataDir()
+	if err := os.MkdirAll(d, os.ModePerm); err != nil {
+		return "", err
+	}
+	return filepath.Join(d, name), nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/deanishe/awgo"
+)
+
+const pinsFile = "pins.json"
+
+// pinMark flags pinned apps in their subtitle.
+const pinMark = "📌"
+
+// pins are full app records so pinned apps can be shown without asking
+// apple, in the order they were pinned.
+func loadPins() ([]app, error) {
+	var pins []app
+	c := aw.NewCache(dataDir())
+	if !c.Exists(pinsFile) {
+		return pins, nil
+	}
+	return pins, c.LoadJSON(pinsFile, &pins)
+}
+
+func savePins(pins []app) error {
+	return aw.NewCache(dataDir()).StoreJSON(pinsFile, pins)
+}
+
+var (
+	pinnedOnce sync.Once
+	pinnedIDs  map[int64]bool
+)
+
+func isPinned(id int64) bool {
+	pinnedOnce.Do(func() {
+		pinnedIDs = map[int64]bool{}
+		pins, err := loadPins()
+		if err != nil {
+			debug("failed to load pins: %s", err.Error())
+		}
+		for _, p := range pins {
+			pinnedIDs[p.ID] = true
+		}
+	})
+	return pinnedIDs[id]
+}
+
+// pinsMatching returns the pinned apps whose name or developer contains
+// every word of term.
+func pinsMatching(term string) []app {
+	pins, err := loadPins()
+	if err != nil {
+		debug("failed to load pins: %s", err.Error())
+		return nil
+	}
+	words := strings.Fields(term)
+	if len(words) == 0 {
+		return nil
+	}
+	var out []app
+	for _, p := range pins {
+		if containsAllWords(p.Name+" "+p.Developer+" "+p.Seller, words) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// withPins puts pinned matches for term at the top of results, dropping
+// their unpinned copies further down.
+func withPins(results []app, term string) []app {
+	pinned := pinsMatching(term)
+	if len(pinned) == 0 {
+		return results
+	}
+	seen := map[int64]bool{}
+	for _, p := range pinned {
+		seen[p.ID] = true
+	}
+	out := append([]app{}, pinned...)
+	for _, res := range results {
+		if !seen[res.ID] {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// pinsFeedback lists every pinned app, for the pins: operator.
+func pinsFeedback(ctx context.Context, fb *aw.Feedback) error {
+	pins, err := loadPins()
+	if err != nil {
+		return err
+	}
+	if len(pins) == 0 {
+		fb.NewItem("no pinned apps").
+			Subtitle("pin one with the pin modifier action on a search result").
+			Icon(aw.IconInfo).
+			Valid(false)
+		return nil
+	}
+	appendApps(ctx, fb, pins)
+	return nil
+}
+
+func init() {
+	registerAction(action{
+		name: "pin",
+		desc: "Pin to the top of searches",
+		arg:  func(res app) string { return fmt.Sprint(res.ID) },
+		subtitle: func(res app) string {
+			if isPinned(res.ID) {
+				return "Unpin"
+			}
+			return "Pin to the top of searches"
+		},
+		run: func(ctx context.Context, arg string) error {
+			return pinsCmd(ctx, []string{"toggle", arg})
+		},
+	})
+	register(command{
+		name:  "pins",
+		usage: "pins list|add|remove|toggle",
+		desc:  "manage the apps pinned to the top of searches",
+		run:   pinsCmd,
+	})
+}
+
+func pinsCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	pins, err := loadPins()
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		for _, p := range pins {
+			fmt.Printf("%d\t%s\t%s\n", p.ID, p.Name, p.Developer)
+		}
+		return nil
+	case "add", "remove", "toggle":
+	default:
+		return fmt.Errorf("unknown pins command %q", args[0])
+	}
+	ids, err := parseIDs(args[1:])
+	if err != nil {
+		return err
+	}
+	have := map[int64]bool{}
+	for _, p := range pins {
+		have[p.ID] = true
+	}
+	var add, remove []int64
+	for _, id := range ids {
+		switch {
+		case args[0] == "remove", args[0] == "toggle" && have[id]:
+			remove = append(remove, id)
+		case !have[id]:
+			add = append(add, id)
+		}
+	}
+	if len(add) > 0 {
+		apps, err := lookupMany(ctx, add)
+		if err != nil {
+			return err
+		}
+		pins = append(pins, apps...)
+	}
+	drop := map[int64]bool{}
+	for _, id := range remove {
+		drop[id] = true
+	}
+	kept := pins[:0]
+	for _, p := range pins {
+		if !drop[p.ID] {
+			kept = append(kept, p)
+		}
+	}
+	return savePins(kept)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+)
+
+// platforms maps the names accepted by the PLATFORMS setting to the search
+// api entity that covers them.
+var platforms = map[string]string{
+	"mac": "macSoftware",
+	"ios": "software",
+}
+
+var platformNames = []string{"mac", "ios"}
+
+// searchEntities are the entities to search, in the order they were
+// configured.
+func searchEntities() []string {
+	var entities []string
+	for _, name := range strings.Split(config("PLATFORMS"), ",") {
+		if e, ok := platforms[strings.TrimSpace(name)]; ok {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+// platformLabel is the platform res was released for, as the app store
+// names it.
+func platformLabel(res app) string {
+	if res.Kind == "mac-software" {
+		return "Mac"
+	}
+	return "iPhone & iPad"
+}
+
+// searchCountries is COUNTRY followed by any EXTRA_COUNTRIES, without
+// repeats.
+func searchCountries() []string {
+	countries := []string{strings.ToLower(config("COUNTRY"))}
+	for _, c := range strings.Split(config("EXTRA_COUNTRIES"), ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c != "" && !containsString(countries, c) {
+			countries = append(countries, c)
+		}
+	}
+	return countries
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeResults interleaves the result lists of several searches, keeping
+// each app once, so that the best hits of every platform and storefront come
+// first. the countries an app turned up in are collected on the one kept.
+func mergeResults(lists [][]app) []app {
+	var (
+		out  []app
+		seen = map[int64]int{}
+	)
+	for i := 0; ; i++ {
+		more := false
+		for _, list := range lists {
+			if i >= len(list) {
+				continue
+			}
+			more = true
+			res := list[i]
+			j, ok := seen[res.ID]
+			if !ok {
+				seen[res.ID] = len(out)
+				out = append(out, res)
+				continue
+			}
+			for _, c := range res.Countries {
+				if !containsString(out[j].Countries, c) {
+					out[j].Countries = append(out[j].Countries, c)
+				}
+			}
+		}
+		if !more {
+			return out
+		}
+	}
+}
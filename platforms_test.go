@@ -0,0 +1,25 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeResults(t *testing.T) {
+	a := app{ID: 1, Countries: []string{"us"}}
+	b := app{ID: 2, Countries: []string{"us"}}
+	a2 := app{ID: 1, Countries: []string{"gb"}}
+	c := app{ID: 3, Countries: []string{"gb"}}
+	got := mergeResults([][]app{{a, b}, {c, a2}, nil})
+	var ids []int64
+	for _, res := range got {
+		ids = append(ids, res.ID)
+	}
+	// interleaved by rank, duplicates folded into the first
+	if want := []int64{1, 3, 2}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("mergeResults() ids = %v, want %v", ids, want)
+	}
+	if want := []string{"us", "gb"}; !reflect.DeepEqual(got[0].Countries, want) {
+		t.Errorf("merged countries = %v, want %v", got[0].Countries, want)
+	}
+}
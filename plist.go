@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// decodePlist reads an xml property list into plain go values: dicts become
+// map[string]interface{}, arrays []interface{}, and scalars string, int64,
+// float64 or bool. some of apple's older store endpoints only speak plist.
+func decodePlist(r io.Reader) (interface{}, error) {
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local != "plist" {
+			return decodePlistValue(d, se)
+		}
+	}
+}
+
+func decodePlistValue(d *xml.Decoder, se xml.StartElement) (interface{}, error) {
+	switch se.Name.Local {
+	case "dict":
+		m := map[string]interface{}{}
+		var key string
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := d.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				v, err := decodePlistValue(d, t)
+				if err != nil {
+					return nil, err
+				}
+				m[key] = v
+			case xml.EndElement:
+				return m, nil
+			}
+		}
+	case "array":
+		var a []interface{}
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				v, err := decodePlistValue(d, t)
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, v)
+			case xml.EndElement:
+				return a, nil
+			}
+		}
+	case "true", "false":
+		return se.Name.Local == "true", d.Skip()
+	}
+	var s string
+	if err := d.DecodeElement(&s, &se); err != nil {
+		return nil, err
+	}
+	switch se.Name.Local {
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	case "string", "date", "data":
+		return s, nil
+	}
+	return nil, fmt.Errorf("unsupported plist element <%s>", se.Name.Local)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const (
+	prefetchStateFile = "prefetch.json"
+	prefetchLockFile  = "prefetch.lock"
+
+	// how often a search is allowed to kick off a background prefetch
+	prefetchInterval = time.Hour * 6
+	// cached results younger than this are not fetched again
+	prefetchMaxAge = time.Hour * 24
+	// a lock older than this belongs to a prefetch that died
+	prefetchLockTTL = time.Minute * 10
+)
+
+func init() {
+	registerRefreshJob("prefetch", func(ctx context.Context) error {
+		return prefetch(ctx, 5)
+	})
+	register(command{
+		name:  "prefetch",
+		usage: "prefetch [-n count]",
+		desc:  "warm the results and icon caches for the most frequent searches and the wishlist",
+		run:   prefetchCmd,
+	})
+}
+
+func prefetchCmd(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("prefetch", flag.ContinueOnError)
+	n := fs.Int("n", 5, "number of searches to prefetch")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return prefetch(ctx, *n)
+}
+
+// prefetch refreshes the cached results and icons of the n most frequent
+// searches, and the icons of wishlist apps.
+func prefetch(ctx context.Context, n int) error {
+	unlock, ok := prefetchLock()
+	if !ok {
+		debug("prefetch already running")
+		return nil
+	}
+	defer unlock()
+	tokenWait = time.Minute
+	if err := aw.NewCache(cacheDir()).StoreJSON(prefetchStateFile, map[string]time.Time{"last": time.Now()}); err != nil {
+		return err
+	}
+	// results are checked where search stores them, whichever backend
+	c := cache()
+	for _, term := range topQueries(n) {
+		q := parseQuery(term)
+		if c.Exists(q.cacheKey()) && !c.Expired(q.cacheKey(), prefetchMaxAge) {
+			continue
+		}
+		debug("prefetching %q", term)
+		results, err := search(ctx, q)
+		if isRateLimit(err) {
+			debug("rate limited, stopping prefetch")
+			return nil
+		} else if err != nil {
+			return err
+		}
+		images := make([]string, len(results))
+		for i, res := range results {
+			images[i] = res.Artwork
+		}
+		downloadAllImages(ctx, downloadConcurrency(), images)
+	}
+	return prefetchWishlist(ctx)
+}
+
+// prefetchWishlist downloads the artwork of every wishlist app, which the
+// wishlist does not keep, with one lookup for all of them.
+func prefetchWishlist(ctx context.Context) error {
+	w, err := loadWishlist()
+	if err != nil || len(w) == 0 {
+		return err
+	}
+	ids := make([]int64, len(w))
+	for i, e := range w {
+		ids[i] = e.ID
+	}
+	apps, err := lookupMany(ctx, ids)
+	if isRateLimit(err) {
+		debug("rate limited, not prefetching wishlist icons")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	images := make([]string, len(apps))
+	for i, res := range apps {
+		images[i] = res.Artwork
+	}
+	debug("prefetching %d wishlist icons", len(images))
+	downloadAllImages(ctx, downloadConcurrency(), images)
+	return nil
+}
+
+// prefetchLock makes sure only one prefetch runs at a time.
+func prefetchLock() (func(), bool) {
+	filename := filepath.Join(cacheDir(), prefetchLockFile)
+	if info, err := os.Stat(filename); err == nil && time.Since(info.ModTime()) > prefetchLockTTL {
+		os.Remove(filename)
+	}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, false
+	}
+	f.Close()
+	return func() { os.Remove(filename) }, true
+}
+
+// maybePrefetch starts a detached prefetch if one has not run recently. it
+// does not wait for it, alfred only cares about our stdout.
+func maybePrefetch() {
+	if !aw.NewCache(cacheDir()).Expired(prefetchStateFile, prefetchInterval) {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		debug("failed to find executable for prefetch: %s", err.Error())
+		return
+	}
+	cmd := exec.Command(exe, "prefetch")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		debug("failed to start prefetch: %s", err.Error())
+		return
+	}
+	debug("started background prefetch (pid %d)", cmd.Process.Pid)
+	cmd.Process.Release()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+func init() {
+	registerRefreshJob("wishlist", checkWishlist)
+}
+
+// checkWishlist looks up every wishlist app, notifying about price drops and
+// new versions since the last check. every change is also logged for the
+// weekly digest, and new versions go into the changelog feed.
+func checkWishlist(ctx context.Context) error {
+	w, err := loadWishlist()
+	if err != nil || len(w) == 0 {
+		return err
+	}
+	ids := make([]int64, len(w))
+	for i, e := range w {
+		ids[i] = e.ID
+	}
+	apps, err := lookupMany(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := map[int64]app{}
+	for _, res := range apps {
+		byID[res.ID] = res
+	}
+	now := time.Now()
+	var changes []wishlistChange
+	var releases []release
+	for i := range w {
+		e := &w[i]
+		res, ok := byID[e.ID]
+		if !ok {
+			continue
+		}
+		storeURL := storeURL(e.ID)
+		change := func(kind, from, to string) {
+			changes = append(changes, wishlistChange{Time: now, ID: e.ID, Name: res.Name, URL: e.URL, Kind: kind, From: from, To: to})
+		}
+		if res.Price != e.Price {
+			change(changePrice, e.PriceFmt, res.PriceFmt)
+		}
+		if e.priceAlert(res.Price) {
+			message := fmt.Sprintf("price dropped from %s to %s", e.PriceFmt, res.PriceFmt)
+			if res.Price == 0 {
+				message = fmt.Sprintf("free right now (was %s)", e.PriceFmt)
+			} else if e.Alert == alertBelow {
+				message = fmt.Sprintf("%s, below your target of %.2f (was %s)", res.PriceFmt, e.Target, e.PriceFmt)
+			}
+			err := notify(ctx, eventPriceDrop, res.Name, message, storeURL)
+			if err != nil {
+				debug("%s", err.Error())
+			}
+		}
+		if e.Version != "" && res.Version != e.Version {
+			change(changeVersion, e.Version, res.Version)
+			date := res.Updated
+			if date.IsZero() {
+				date = now
+			}
+			releases = append(releases, release{ID: e.ID, Name: res.Name, URL: e.URL, Version: res.Version, Notes: res.ReleaseNotes, Date: date})
+			err := notify(ctx, eventUpdate, res.Name, fmt.Sprintf("version %s is out (was %s)", res.Version, e.Version), storeURL)
+			if err != nil {
+				debug("%s", err.Error())
+			}
+		}
+		if e.Rating != 0 && math.Abs(res.Rating-e.Rating) >= 0.1 {
+			change(changeRating, fmt.Sprintf("%.1f", e.Rating), fmt.Sprintf("%.1f", res.Rating))
+		}
+		e.Name = res.Name
+		e.Price = res.Price
+		e.PriceFmt = res.PriceFmt
+		e.Version = res.Version
+		e.Rating = res.Rating
+		e.Checked = now
+	}
+	if err := saveWishlist(w); err != nil {
+		return err
+	}
+	if err := logWishlistChanges(changes); err != nil {
+		return err
+	}
+	if err := logReleases(releases); err != nil {
+		return err
+	}
+	return maybeWriteDigest(ctx)
+}
+
+// priceAlert reports whether the price going from e's to price is worth
+// a notification under e's alert mode.
+func (e wishlistEntry) priceAlert(price float64) bool {
+	switch e.Alert {
+	case alertFree:
+		return price == 0 && e.Price > 0
+	case alertBelow:
+		// only when it crosses the target, not on every check below it
+		return price < e.Target && e.Price >= e.Target
+	}
+	return price < e.Price
+}
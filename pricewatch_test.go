@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestPriceAlert(t *testing.T) {
+	tests := []struct {
+		name   string
+		entry  wishlistEntry
+		price  float64
+		notify bool
+	}{
+		{"drop", wishlistEntry{Price: 9.99}, 4.99, true},
+		{"same price", wishlistEntry{Price: 9.99}, 9.99, false},
+		{"price went up", wishlistEntry{Price: 4.99}, 9.99, false},
+		{"explicit drop", wishlistEntry{Price: 9.99, Alert: alertDrop}, 0, true},
+		{"free, from paid", wishlistEntry{Price: 9.99, Alert: alertFree}, 0, true},
+		{"free, only cheaper", wishlistEntry{Price: 9.99, Alert: alertFree}, 0.99, false},
+		{"free, was free already", wishlistEntry{Price: 0, Alert: alertFree}, 0, false},
+		{"below, crossing the target", wishlistEntry{Price: 9.99, Alert: alertBelow, Target: 5}, 4.99, true},
+		{"below, not yet", wishlistEntry{Price: 9.99, Alert: alertBelow, Target: 5}, 6.99, false},
+		{"below, at the target", wishlistEntry{Price: 9.99, Alert: alertBelow, Target: 5}, 5, false},
+		{"below, already below", wishlistEntry{Price: 4.99, Alert: alertBelow, Target: 5}, 3.99, false},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.priceAlert(tt.price); got != tt.notify {
+			t.Errorf("%s: priceAlert(%.2f) = %t, want %t", tt.name, tt.price, got, tt.notify)
+		}
+	}
+}
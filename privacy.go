@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+type privacyType struct {
+	Type       string `json:"privacyType"`
+	Detail     string `json:"detail"`
+	Categories []struct {
+		Category  string   `json:"dataCategory"`
+		DataTypes []string `json:"dataTypes"`
+	} `json:"dataCategories"`
+	Purposes []struct {
+		Purpose    string `json:"purpose"`
+		Categories []struct {
+			Category  string   `json:"dataCategory"`
+			DataTypes []string `json:"dataTypes"`
+		} `json:"dataCategories"`
+	} `json:"purposes"`
+}
+
+// privacyLabels fetches an app's privacy "nutrition label".
+func privacyLabels(ctx context.Context, id int64) ([]privacyType, error) {
+	params := url.Values{}
+	params.Set("platform", "web")
+	params.Set("fields", "privacyDetails")
+	params.Set("l", "en-US")
+	var body struct {
+		Data []struct {
+			Attributes struct {
+				PrivacyDetails struct {
+					PrivacyTypes []privacyType `json:"privacyTypes"`
+				} `json:"privacyDetails"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	err := amp(ctx, fmt.Sprintf("catalog/%s/apps/%d", config("COUNTRY"), id), params, &body)
+	if err != nil {
+		return nil, err
+	}
+	if len(body.Data) == 0 {
+		return nil, nil
+	}
+	return body.Data[0].Attributes.PrivacyDetails.PrivacyTypes, nil
+}
+
+// privacyFeedback is the drill-down view for an app's privacy label, reached
+// from the detail view (which autocompletes to "privacy:<trackId>").
+func privacyFeedback(ctx context.Context, id int64) (*aw.Feedback, error) {
+	fb := aw.NewFeedback()
+	types, err := privacyLabels(ctx, id)
+	if err != nil {
+		fb.NewItem("could not load privacy details").
+			Subtitle(err.Error()).
+			Icon(aw.IconWarning).
+			Valid(false)
+		return fb, nil
+	}
+	if len(types) == 0 {
+		fb.NewItem("the developer has not provided privacy details").
+			Icon(aw.IconInfo).
+			Valid(false)
+		return fb, nil
+	}
+	for _, t := range types {
+		var cats []string
+		for _, c := range t.Categories {
+			cats = append(cats, c.Category)
+		}
+		for _, p := range t.Purposes {
+			for _, c := range p.Categories {
+				cats = append(cats, c.Category)
+			}
+		}
+		cats = uniqueStrings(cats)
+		sub := strings.Join(cats, ", ")
+		if sub == "" {
+			sub = t.Detail
+		}
+		fb.NewItem(t.Type).
+			Subtitle(sub).
+			Largetype(privacyText(t)).
+			Copytext(privacyText(t)).
+			Icon(aw.IconInfo).
+			Valid(false)
+	}
+	return fb, nil
+}
+
+// privacyText spells a privacy type out in full, for large type.
+func privacyText(t privacyType) string {
+	var b strings.Builder
+	b.WriteString(t.Type + "\n")
+	for _, c := range t.Categories {
+		fmt.Fprintf(&b, "\n%s: %s", c.Category, strings.Join(c.DataTypes, ", "))
+	}
+	for _, p := range t.Purposes {
+		fmt.Fprintf(&b, "\n\n%s", p.Purpose)
+		for _, c := range p.Categories {
+			fmt.Fprintf(&b, "\n  %s: %s", c.Category, strings.Join(c.DataTypes, ", "))
+		}
+	}
+	return b.String()
+}
+
+func uniqueStrings(ss []string) []string {
+	seen := map[string]bool{}
+	out := ss[:0]
+	for _, s := range ss {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
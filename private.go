@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func init() {
+	register(command{
+		name:  "purge",
+		usage: "purge [-index]",
+		desc:  "delete search history, recently viewed apps, picks, cached results and logs",
+		run:   purgeCmd,
+	})
+}
+
+// private reports whether PRIVATE is on, in which case nothing that says
+// what was searched for or picked is written to disk: no search history,
+// recently viewed or frecency data, cached results or local index.
+func private() bool {
+	return config("PRIVATE") == "1"
+}
+
+// purgedCachePrefixes are the cache entries purge deletes.
+var purgedCachePrefixes = []string{resultsCachePrefix, enrichCachePrefix, storefrontsCachePrefix, reviewsCachePrefix, vppCachePrefix}
+
+func purgeCmd(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	index := fs.Bool("index", false, "also delete the local index of seen apps")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	files := []string{
+		filepath.Join(dataDir(), historyFile),
+		filepath.Join(dataDir(), recentFile),
+		filepath.Join(dataDir(), frecencyFile),
+		// the crash log has the arguments, the others urls and responses
+		// of searches
+		filepath.Join(dataDir(), crashFile),
+		filepath.Join(cacheDir(), traceFile),
+		filepath.Join(cacheDir(), lastResponseFile),
+	}
+	if *index {
+		files = append(files, filepath.Join(dataDir(), indexFile))
+	}
+	// cached results are named after the query they answer, enriched
+	// details, storefronts, reviews and vpp status after apps that were
+	// looked at. whichever backend is in use now, the others may still
+	// hold some
+	for _, prefix := range purgedCachePrefixes {
+		cached, err := filepath.Glob(filepath.Join(cacheDir(), prefix+"*"))
+		if err != nil {
+			return err
+		}
+		files = append(files, cached...)
+	}
+	var deleted int
+	for _, f := range files {
+		if err := os.Remove(f); err == nil {
+			deleted++
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if sqliteFile := filepath.Join(cacheDir(), sqliteCacheFile); fileExists(sqliteFile) {
+		c := sqliteCache{filename: sqliteFile}
+		for _, prefix := range purgedCachePrefixes {
+			if _, err := c.exec("DELETE FROM cache WHERE name LIKE '" + prefix + "%';\n"); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Printf("deleted %d files\n", deleted)
+	return nil
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
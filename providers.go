@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+// provider is a catalog apps can be searched in. the app store is one,
+// others register themselves in init functions and are switched on with the
+// PROVIDERS setting.
+type provider interface {
+	// name is how the provider is referred to in PROVIDERS and from:
+	name() string
+	// label is shown on results from the provider
+	label() string
+	search(ctx context.Context, q query) ([]app, error)
+}
+
+// catalogModifiers is implemented by providers whose results can do more
+// than open a page, e.g. download.
+type catalogModifiers interface {
+	modifiers(item *aw.Item, res app)
+}
+
+var providers = map[string]provider{}
+
+func registerProvider(p provider) {
+	if _, ok := providers[p.name()]; ok {
+		panic(fmt.Sprintf("provider %q registered twice", p.name()))
+	}
+	providers[p.name()] = p
+}
+
+func providerNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		if name != allProviders {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	registerProvider(aggregate{})
+}
+
+// enabledProviders are the providers named in PROVIDERS, in that order.
+func enabledProviders() []provider {
+	var enabled []provider
+	for _, name := range strings.Split(config("PROVIDERS"), ",") {
+		if p, ok := providers[strings.TrimSpace(name)]; ok && p.name() != allProviders {
+			enabled = append(enabled, p)
+		}
+	}
+	return enabled
+}
+
+func providerEnabled(name string) bool {
+	for _, p := range enabledProviders() {
+		if p.name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkProviders validates PROVIDERS. like actions, providers register in
+// init functions, so this has to look them up lazily.
+func checkProviders(v string) error {
+	for _, name := range strings.Split(v, ",") {
+		if _, ok := providers[strings.TrimSpace(name)]; !ok || strings.TrimSpace(name) == allProviders {
+			return fmt.Errorf("unknown provider %q, expected one of %s", name, strings.Join(providerNames(), ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	registerAction(action{
+		name: "qr-code",
+		desc: "Show a QR code to open this app on a phone",
+		arg:  func(res app) string { return res.URL },
+		run:  showQRCode,
+	})
+}
+
+// qrScript draws argv[0] as a qr code png at argv[1], with core image's
+// generator through the javascript for automation objc bridge, so no qr
+// encoder has to be shipped.
+const qrScript = `ObjC.import('AppKit');
+ObjC.import('CoreImage');
+function run(argv) {
+	var filter = $.CIFilter.filterWithName('CIQRCodeGenerator');
+	filter.setValueForKey($(argv[0]).dataUsingEncoding($.NSUTF8StringEncoding), 'inputMessage');
+	filter.setValueForKey($('M'), 'inputCorrectionLevel');
+	var image = filter.outputImage.imageByApplyingTransform({a: 12, b: 0, c: 0, d: 12, tx: 0, ty: 0});
+	var rep = $.NSBitmapImageRep.alloc.initWithCIImage(image);
+	var png = rep.representationUsingTypeProperties($.NSBitmapImageFileTypePNG, $());
+	if (!png.writeToFileAtomically(argv[1], true)) {
+		throw new Error('could not write ' + argv[1]);
+	}
+}`
+
+// showQRCode renders link as a qr code and opens it in quick look, for
+// scanning with a phone's camera to install an ios app there.
+func showQRCode(ctx context.Context, link string) error {
+	dir := filepath.Join(cacheDir(), "qr")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, md5hash(link)+".png")
+	if _, err := os.Stat(filename); err != nil {
+		out, err := exec.CommandContext(ctx, "osascript", "-l", "JavaScript", "-e", qrScript, link, filename).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to draw qr code: %s: %s", err.Error(), strings.TrimSpace(string(out)))
+		}
+	}
+	// quick look stays up until it is dismissed, there is nothing to wait for
+	return exec.Command("qlmanage", "-p", filename).Start()
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// query is a parsed script filter query. free text goes to the search api as
+// is, operators (key:value, value may be "quoted") narrow the results down.
+type query struct {
+	id         int64
+	privacy    int64
+	compare    int64
+	games      *string
+	trending   bool
+	pins       bool
+	recent     bool
+	desc       *string
+	saved      *string
+	saveAs     *string
+	setup      *string
+	mine       *string
+	from       string
+	terms      []string
+	phrases    []string
+	exclude    []string
+	developer  string
+	minRatings int
+	maxAge     int
+	page       int
+}
+
+func parseQuery(raw string) query {
+	q := query{
+		minRatings: configInt("MIN_RATINGS"),
+		maxAge:     ageRating(config("MAX_AGE_RATING")),
+		page:       1,
+	}
+	toks, quoted := tokenizeQuoted(normalizeQuery(raw))
+	for i, tok := range toks {
+		if len(tok) > 1 && tok[0] == '-' && !quoted[i] {
+			q.exclude = append(q.exclude, tok[1:])
+			continue
+		}
+		if id, ok := storeLinkID(tok); ok && !quoted[i] {
+			q.id = id
+			continue
+		}
+		key, value, ok := splitOperator(tok)
+		if !ok || quoted[i] {
+			q.terms = append(q.terms, tok)
+			if quoted[i] {
+				q.phrases = append(q.phrases, tok)
+			}
+			continue
+		}
+		switch key {
+		case "id":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				q.terms = append(q.terms, tok)
+				continue
+			}
+			q.id = id
+		case "compare":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				q.terms = append(q.terms, tok)
+				continue
+			}
+			q.compare = id
+		case "trending":
+			q.trending = true
+		case "pins":
+			q.pins = true
+		case "recent":
+			q.recent = true
+		case "desc":
+			q.desc = &value
+		case "saved":
+			q.saved = &value
+		case "save":
+			q.saveAs = &value
+		case "setup":
+			q.setup = &value
+		case "mine":
+			q.mine = &value
+		case "games":
+			q.games = &value
+		case "privacy":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				q.terms = append(q.terms, tok)
+				continue
+			}
+			q.privacy = id
+		case "from":
+			q.from = value
+		case "page":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				q.terms = append(q.terms, tok)
+				continue
+			}
+			q.page = n
+		case "developer", "dev":
+			q.developer = value
+		case "minratings":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				q.terms = append(q.terms, tok)
+				continue
+			}
+			q.minRatings = n
+		default:
+			q.terms = append(q.terms, tok)
+		}
+	}
+	return q
+}
+
+// term is what gets sent to the search api. a query with nothing but a
+// developer operator searches for that developer instead.
+func (q query) term() string {
+	if len(q.terms) == 0 {
+		return q.developer
+	}
+	return strings.Join(q.terms, " ")
+}
+
+// attribute narrows which field the search api matches term against.
+func (q query) attribute() string {
+	if len(q.terms) == 0 && q.developer != "" {
+		return "softwareDeveloper"
+	}
+	return ""
+}
+
+// provider is the enabled provider named by from:, all of them for
+// from:all, or else the first enabled one.
+func (q query) provider() provider {
+	if q.from == allProviders {
+		return providers[allProviders]
+	}
+	enabled := enabledProviders()
+	for _, p := range enabled {
+		if p.name() == q.from {
+			return p
+		}
+	}
+	if len(enabled) > 0 {
+		return enabled[0]
+	}
+	return providers[appStoreProvider]
+}
+
+// cacheKey identifies the api requests q turns into.
+func (q query) cacheKey() string {
+	return resultsCacheKey(strings.Join([]string{
+		q.provider().name(),
+		config("PROVIDERS"),
+		config("COUNTRY"),
+		strconv.Itoa(q.limit()),
+		config("BACKEND"),
+		config("PLATFORMS"),
+		config("EXTRA_COUNTRIES"),
+		q.attribute(),
+		q.term(),
+	}, ":"))
+}
+
+func (q query) filter(results []app) []app {
+	out := results[:0]
+	for _, res := range results {
+		if q.developer != "" && !matchesDeveloper(res, q.developer) {
+			continue
+		}
+		if res.NumRatings < q.minRatings {
+			continue
+		}
+		if q.maxAge > 0 && ageRating(res.AgeRating) > q.maxAge {
+			continue
+		}
+		if !q.matchesPhrases(res) {
+			continue
+		}
+		if q.excludes(res) {
+			continue
+		}
+		out = append(out, res)
+	}
+	return out
+}
+
+// matchesPhrases reports whether res's name contains every "quoted phrase"
+// of the query, so multi word names are not drowned out by apps that merely
+// mention each word somewhere.
+func (q query) matchesPhrases(res app) bool {
+	name := normalizeQuery(res.Name)
+	for _, p := range q.phrases {
+		if !strings.Contains(name, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// excludes reports whether res matches any -excluded term, by name or
+// developer.
+func (q query) excludes(res app) bool {
+	for _, ex := range q.exclude {
+		if strings.Contains(normalizeQuery(res.Name), ex) || matchesDeveloper(res, ex) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesDeveloper(res app, name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(strings.ToLower(res.Seller), name) ||
+		strings.Contains(strings.ToLower(res.Developer), name)
+}
+
+// ageRating turns an advisory rating like "12+" into its minimum age. unknown
+// or empty ratings are 0, which never gets filtered.
+func ageRating(s string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(s), "+"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// tokenize splits s on whitespace, except inside double quotes. quotes are
+// stripped from the resulting tokens.
+func tokenize(s string) []string {
+	toks, _ := tokenizeQuoted(s)
+	return toks
+}
+
+// tokenizeQuoted is tokenize, also reporting which tokens were "quoted" as
+// a whole.
+func tokenizeQuoted(s string) ([]string, []bool) {
+	var (
+		toks     []string
+		quotes   []bool
+		cur      strings.Builder
+		quoted   bool
+		started  bool
+		hadQuote bool
+	)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			// only a token that opens with a quote is a phrase,
+			// developer:"name" is still an operator
+			if !started {
+				hadQuote = true
+			}
+			quoted = !quoted
+			started = true
+		case unicode.IsSpace(r) && !quoted:
+			if started {
+				toks = append(toks, cur.String())
+				quotes = append(quotes, hadQuote)
+				cur.Reset()
+				started = false
+				hadQuote = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		toks = append(toks, cur.String())
+		quotes = append(quotes, hadQuote)
+	}
+	return toks, quotes
+}
+
+// splitOperator splits key:value. the value may be empty ("games:" is the
+// start of browsing).
+func splitOperator(tok string) (key, value string, ok bool) {
+	i := strings.IndexByte(tok, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	return strings.ToLower(tok[:i]), tok[i+1:], true
+}
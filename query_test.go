@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeQuoted(t *testing.T) {
+	tests := []struct {
+		in     string
+		toks   []string
+		quoted []bool
+	}{
+		{"", nil, nil},
+		{"  things  3 ", []string{"things", "3"}, []bool{false, false}},
+		{`"to do" list`, []string{"to do", "list"}, []bool{true, false}},
+		{`developer:"the omni group" outliner`, []string{"developer:the omni group", "outliner"}, []bool{false, false}},
+		{`"unterminated phrase`, []string{"unterminated phrase"}, []bool{true}},
+		{`""`, []string{""}, []bool{true}},
+	}
+	for _, tt := range tests {
+		toks, quoted := tokenizeQuoted(tt.in)
+		if !reflect.DeepEqual(toks, tt.toks) || !reflect.DeepEqual(quoted, tt.quoted) {
+			t.Errorf("tokenizeQuoted(%q) = %q %v, want %q %v", tt.in, toks, quoted, tt.toks, tt.quoted)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want func(q query) bool
+	}{
+		{"Things 3", func(q query) bool {
+			return reflect.DeepEqual(q.terms, []string{"things", "3"}) && q.page == 1
+		}},
+		{`"to do" list`, func(q query) bool {
+			return reflect.DeepEqual(q.terms, []string{"to do", "list"}) &&
+				reflect.DeepEqual(q.phrases, []string{"to do"})
+		}},
+		{`"to do" -free`, func(q query) bool {
+			return reflect.DeepEqual(q.terms, []string{"to do"}) &&
+				reflect.DeepEqual(q.exclude, []string{"free"})
+		}},
+		{"id:497799835", func(q query) bool { return q.id == 497799835 && len(q.terms) == 0 }},
+		{"id:abc", func(q query) bool { return q.id == 0 && reflect.DeepEqual(q.terms, []string{"id:abc"}) }},
+		{`"id:1"`, func(q query) bool { return q.id == 0 && reflect.DeepEqual(q.terms, []string{"id:1"}) }},
+		{"https://apps.apple.com/us/app/things-3/id904280696?mt=12", func(q query) bool { return q.id == 904280696 && len(q.terms) == 0 }},
+		{"https://example.com/app/id904280696", func(q query) bool {
+			return q.id == 0 && reflect.DeepEqual(q.terms, []string{"https://example.com/app/id904280696"})
+		}},
+		{`dev:"Cultured Code"`, func(q query) bool {
+			return q.developer == "cultured code" && q.term() == "cultured code" && q.attribute() == "softwareDeveloper"
+		}},
+		{"notes dev:apple", func(q query) bool { return q.term() == "notes" && q.attribute() == "" }},
+		{"notes page:3", func(q query) bool { return q.page == 3 && q.term() == "notes" }},
+		{"notes page:0", func(q query) bool { return q.page == 1 && reflect.DeepEqual(q.terms, []string{"notes", "page:0"}) }},
+		{"minratings:50 pdf", func(q query) bool { return q.minRatings == 50 && q.term() == "pdf" }},
+		{"from:homebrew editor", func(q query) bool { return q.from == "homebrew" && q.term() == "editor" }},
+		{"games:", func(q query) bool { return q.games != nil && *q.games == "" }},
+		{"mine: notes", func(q query) bool { return q.mine != nil && *q.mine == "" && q.term() == "notes" }},
+		{"re:mind me", func(q query) bool { return reflect.DeepEqual(q.terms, []string{"re:mind", "me"}) }},
+		{"-", func(q query) bool { return reflect.DeepEqual(q.terms, []string{"-"}) && len(q.exclude) == 0 }},
+	}
+	for _, tt := range tests {
+		if q := parseQuery(tt.in); !tt.want(q) {
+			t.Errorf("parseQuery(%q) = %+v", tt.in, q)
+		}
+	}
+}
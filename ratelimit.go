@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const (
+	rateLimitFile = "ratelimit.json"
+
+	// apple does not document the search api's limits. these start small and
+	// double every time we get throttled again without a success in between.
+	minBackOff = time.Second * 30
+	maxBackOff = time.Minute * 15
+)
+
+var (
+	errRateLimited = errors.New("rate limited by the app store")
+	// errThrottled is our own limit, API_RATE_LIMIT, not apple's
+	errThrottled = errors.New("too many searches, slowing down")
+)
+
+// isRateLimit reports whether err means a request was held back, by apple or
+// by us, rather than failed.
+func isRateLimit(err error) bool {
+	return err == errRateLimited || err == errThrottled
+}
+
+type rateLimitState struct {
+	Until   time.Time `json:"until"`
+	Strikes int       `json:"strikes"`
+}
+
+func isRateLimitStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusForbidden
+}
+
+func loadRateLimit() rateLimitState {
+	var s rateLimitState
+	c := aw.NewCache(cacheDir())
+	if c.Exists(rateLimitFile) {
+		if err := c.LoadJSON(rateLimitFile, &s); err != nil {
+			debug("failed to load rate limit state: %s", err.Error())
+		}
+	}
+	return s
+}
+
+func rateLimited() bool {
+	return time.Now().Before(loadRateLimit().Until)
+}
+
+// backOff records that apple throttled resp's request. Retry-After is honored
+// when present, otherwise the wait grows exponentially with each strike.
+func backOff(resp *http.Response) {
+	s := loadRateLimit()
+	s.Strikes++
+	wait := minBackOff << uint(s.Strikes-1)
+	if wait > maxBackOff || wait <= 0 {
+		wait = maxBackOff
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		wait = time.Duration(secs) * time.Second
+	}
+	s.Until = time.Now().Add(wait)
+	debug("rate limited (%d), backing off for %s", resp.StatusCode, wait)
+	if err := aw.NewCache(cacheDir()).StoreJSON(rateLimitFile, s); err != nil {
+		debug("failed to store rate limit state: %s", err.Error())
+	}
+}
+
+func clearBackOff() {
+	if err := aw.NewCache(cacheDir()).Store(rateLimitFile, nil); err != nil {
+		debug("failed to clear rate limit state: %s", err.Error())
+	}
+}
+
+// limitItem explains err, one of the rate limit errors. cached is set when
+// results (cached, or those that did get through) are shown anyway.
+func limitItem(err error, cached bool) *aw.Item {
+	if err != errThrottled {
+		return rateLimitItem(cached)
+	}
+	subtitle := "no cached results for this search, try again in a bit"
+	if cached {
+		subtitle = "showing what is cached or got through"
+	}
+	return new(aw.Item).
+		Title("Slowing down to stay under API_RATE_LIMIT").
+		Subtitle(subtitle).
+		Icon(aw.IconWarning).
+		Valid(false)
+}
+
+func rateLimitItem(cached bool) *aw.Item {
+	subtitle := "no cached results for this search, try again in a bit"
+	if cached {
+		subtitle = "showing cached results"
+	}
+	if s := loadRateLimit(); time.Now().Before(s.Until) {
+		subtitle += " · retrying after " + s.Until.Format("15:04:05")
+	}
+	return withReportIssue(new(aw.Item).
+		Title("rate limited by the app store").
+		Subtitle(subtitle).
+		Icon(aw.IconWarning).
+		Valid(false), "rate limited by the app store")
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const (
+	reviewsTTL         = time.Hour * 6
+	reviewsCachePrefix = "reviews-"
+	// a current version rated this much below the lifetime average is
+	// flagged, it usually means an update went badly
+	ratingDropWarning = 0.5
+)
+
+// versionRating is how the current version of an app is rated, apart from
+// the average over every version.
+type versionRating struct {
+	Version string  `json:"version"`
+	Rating  float64 `json:"rating"`
+	Count   int     `json:"count"`
+	// Sampled is set when apple did not say and the rating was worked out
+	// from recent reviews
+	Sampled bool `json:"sampled"`
+}
+
+type reviewEntry struct {
+	Rating  label `json:"im:rating"`
+	Version label `json:"im:version"`
+}
+
+// reviewEntries is rssEntries for the reviews feed.
+type reviewEntries []reviewEntry
+
+func (e *reviewEntries) UnmarshalJSON(b []byte) error {
+	var list []reviewEntry
+	if err := json.Unmarshal(b, &list); err == nil {
+		*e = list
+		return nil
+	}
+	var one reviewEntry
+	if err := json.Unmarshal(b, &one); err != nil {
+		return err
+	}
+	*e = reviewEntries{one}
+	return nil
+}
+
+// currentVersionRating returns the rating of res's current version. the
+// lookup api sends it for most apps, for the rest it is averaged from the
+// most recent reviews of that version.
+func currentVersionRating(ctx context.Context, res app) (versionRating, error) {
+	if res.CurrentNumRatings > 0 {
+		return versionRating{Version: res.Version, Rating: res.CurrentRating, Count: res.CurrentNumRatings}, nil
+	}
+	var (
+		reviews []reviewEntry
+		err     error
+	)
+	if private() {
+		// the cache would say which apps were looked at
+		reviews, err = fetchReviews(ctx, res.ID)
+	} else {
+		key := fmt.Sprintf("%s%s-%d.json", reviewsCachePrefix, config("COUNTRY"), res.ID)
+		err = cache().LoadOrStoreJSON(key, reviewsTTL, func() (interface{}, error) {
+			return fetchReviews(ctx, res.ID)
+		}, &reviews)
+	}
+	if err != nil {
+		return versionRating{}, err
+	}
+	vr := versionRating{Version: res.Version, Sampled: true}
+	var sum int
+	for _, r := range reviews {
+		n, err := strconv.Atoi(r.Rating.Label)
+		if err != nil || r.Version.Label != res.Version {
+			continue
+		}
+		sum += n
+		vr.Count++
+	}
+	if vr.Count > 0 {
+		vr.Rating = float64(sum) / float64(vr.Count)
+	}
+	return vr, nil
+}
+
+// fetchReviews gets the first page of the app's most recent reviews.
+func fetchReviews(ctx context.Context, id int64) ([]reviewEntry, error) {
+	data, err := itunesGet(ctx, fmt.Sprintf("%s/rss/customerreviews/id=%d/sortby=mostrecent/json", config("COUNTRY"), id), nil)
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Feed struct {
+			Entry reviewEntries `json:"entry"`
+		} `json:"feed"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	// the feed starts with an entry for the app itself, which has no rating
+	var reviews []reviewEntry
+	for _, e := range body.Feed.Entry {
+		if e.Rating.Label != "" {
+			reviews = append(reviews, e)
+		}
+	}
+	return reviews, nil
+}
+
+// ratingItems compare the current version's rating with the lifetime
+// average in the detail view, which the combined number hides.
+func ratingItems(ctx context.Context, res app) []*aw.Item {
+	if res.NumRatings == 0 {
+		return nil
+	}
+	lifetime := new(aw.Item).
+		Title(strings.TrimSpace(fmt.Sprintf("%.1f %s", res.Rating, ratingGlyph()))).
+		Subtitle(fmt.Sprintf("average over every version, %s ratings", ratingCount(res.NumRatings))).
+		Icon(aw.IconInfo).
+		Valid(false)
+	vr, err := currentVersionRating(ctx, res)
+	if err != nil {
+		debug("failed to get the current version's rating: %s", err.Error())
+		return []*aw.Item{lifetime}
+	}
+	if vr.Count == 0 {
+		return []*aw.Item{lifetime, new(aw.Item).
+			Title("No ratings for " + vr.Version + " yet").
+			Subtitle("current version").
+			Icon(aw.IconInfo).
+			Valid(false)}
+	}
+	sub := fmt.Sprintf("current version %s, %s ratings", vr.Version, ratingCount(vr.Count))
+	if vr.Sampled {
+		sub = fmt.Sprintf("current version %s, from %d recent reviews", vr.Version, vr.Count)
+	}
+	icon := aw.IconInfo
+	if drop := res.Rating - vr.Rating; drop >= ratingDropWarning {
+		sub = fmt.Sprintf("%s ↓%.1f since the update · %s", staleMark, math.Round(drop*10)/10, sub)
+		icon = aw.IconWarning
+	}
+	current := new(aw.Item).
+		Title(strings.TrimSpace(fmt.Sprintf("%.1f %s", vr.Rating, ratingGlyph()))).
+		Subtitle(sub).
+		Icon(icon).
+		Valid(false)
+	return []*aw.Item{current, lifetime}
+}
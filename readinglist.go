@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func init() {
+	registerAction(action{
+		name: "reading-list",
+		desc: "Add to Safari's Reading List",
+		arg:  func(res app) string { return res.URL },
+		run:  addToReadingList,
+	})
+}
+
+// addToReadingList saves the store page to safari's reading list, titled
+// with the app's name from the app_name variable.
+func addToReadingList(ctx context.Context, link string) error {
+	out, err := exec.CommandContext(
+		ctx,
+		"osascript",
+		"-e", "on run argv",
+		"-e", `tell application "Safari" to add reading list item (item 1 of argv) with title (item 2 of argv)`,
+		"-e", "end run",
+		link,
+		os.Getenv("app_name"),
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to add to reading list: %s: %s", err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
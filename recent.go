@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const (
+	recentFile = "recent.json"
+	// maxRecent is how many viewed apps are remembered
+	maxRecent = 50
+)
+
+type recentEntry struct {
+	App    app       `json:"app"`
+	Viewed time.Time `json:"viewed"`
+}
+
+func loadRecent() []recentEntry {
+	var recent []recentEntry
+	c := aw.NewCache(dataDir())
+	if c.Exists(recentFile) {
+		if err := c.LoadJSON(recentFile, &recent); err != nil {
+			debug("failed to load recently viewed apps: %s", err.Error())
+		}
+	}
+	return recent
+}
+
+// recordViewed moves res to the top of the recently viewed apps.
+func recordViewed(res app) {
+	if private() {
+		return
+	}
+	recent := []recentEntry{{App: res, Viewed: time.Now()}}
+	for _, e := range loadRecent() {
+		if e.App.ID != res.ID && len(recent) < maxRecent {
+			recent = append(recent, e)
+		}
+	}
+	if err := aw.NewCache(dataDir()).StoreJSON(recentFile, recent); err != nil {
+		debug("failed to store recently viewed apps: %s", err.Error())
+	}
+}
+
+// recordViewedID is recordViewed for when only the track id is at hand. the
+// app is nearly always in the local index already, apple is asked otherwise.
+func recordViewedID(ctx context.Context, id string) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return
+	}
+	res := indexedApp(n)
+	if res == nil {
+		if res, err = lookup(ctx, n); err != nil || res == nil {
+			return
+		}
+	}
+	recordViewed(*res)
+}
+
+// viewActions are the actions that count as looking at an app.
+var viewActions = map[string]bool{
+	"open":         true,
+	"open-other":   true,
+	"open-store":   true,
+	"open-browser": true,
+}
+
+// recentFeedback lists recently viewed apps, newest first, narrowed down to
+// those whose name or developer contains every word of term.
+func recentFeedback(ctx context.Context, fb *aw.Feedback, term string) {
+	var (
+		apps   []app
+		viewed []time.Time
+	)
+	words := strings.Fields(term)
+	var (
+		matched []app
+		times   []time.Time
+	)
+	for _, e := range loadRecent() {
+		if containsAllWords(e.App.Name+" "+e.App.Developer, words) {
+			matched = append(matched, e.App)
+			times = append(times, e.Viewed)
+		}
+	}
+	for _, i := range unblockedIndexes(matched) {
+		apps = append(apps, matched[i])
+		viewed = append(viewed, times[i])
+	}
+	if len(apps) == 0 {
+		fb.NewItem("no recently viewed apps").
+			Subtitle("apps show up here once you open their detail view or store page").
+			Icon(aw.IconInfo).
+			Valid(false)
+		return
+	}
+	offset := len(fb.Items)
+	appendApps(ctx, fb, apps)
+	for i, it := range fb.Items[offset:] {
+		it.Subtitle("viewed " + relativeTime(viewed[i]) + " | " + subtitle(apps[i]))
+	}
+}
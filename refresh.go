@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const refreshStateFile = "refresh.json"
+
+type refreshJob struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// refreshJobs is the background work run by "refresh", either by hand or
+// periodically by the launch agent.
+var refreshJobs []refreshJob
+
+func registerRefreshJob(name string, run func(ctx context.Context) error) {
+	refreshJobs = append(refreshJobs, refreshJob{name, run})
+}
+
+type refreshState struct {
+	Last   time.Time         `json:"last"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+func init() {
+	register(command{
+		name:  "refresh",
+		usage: "refresh [-only job]",
+		desc:  "run the background refresh jobs",
+		run:   refreshCmd,
+	})
+}
+
+func refreshCmd(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("refresh", flag.ContinueOnError)
+	only := fs.String("only", "", "only run the named job")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// nobody is waiting on the agent, so it can queue for the rate limiter
+	tokenWait = time.Minute * 5
+	state := refreshState{Last: time.Now(), Errors: map[string]string{}}
+	for _, job := range refreshJobs {
+		if *only != "" && job.name != *only {
+			continue
+		}
+		debug("running refresh job %s", job.name)
+		// one failing job should not keep the others from running
+		if err := job.run(ctx); err != nil {
+			debug("refresh job %s failed: %s", job.name, err.Error())
+			state.Errors[job.name] = err.Error()
+		}
+	}
+	return aw.NewCache(cacheDir()).StoreJSON(refreshStateFile, state)
+}
+
+func loadRefreshState() (refreshState, bool) {
+	var s refreshState
+	c := aw.NewCache(cacheDir())
+	if !c.Exists(refreshStateFile) {
+		return s, false
+	}
+	if err := c.LoadJSON(refreshStateFile, &s); err != nil {
+		debug("failed to load refresh state: %s", err.Error())
+		return s, false
+	}
+	return s, true
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func init() {
+	registerAction(action{
+		name: "remind",
+		desc: "Remind me about this app",
+		arg:  func(res app) string { return res.URL },
+		run:  remind,
+	})
+}
+
+// remind files a to-do for evaluating the app later, in Reminders or Things
+// depending on the REMINDER_APP setting. the app's name comes in through the
+// app_name variable, the store link is the arg.
+func remind(ctx context.Context, link string) error {
+	title := "Check out " + os.Getenv("app_name")
+	switch config("REMINDER_APP") {
+	case "things":
+		q := url.Values{}
+		q.Set("title", title)
+		q.Set("notes", link)
+		u := "things:///add?" + encodeSpaces(q.Encode())
+		return openURL(ctx, u)
+	default:
+		out, err := exec.CommandContext(
+			ctx,
+			"osascript",
+			"-e", "on run argv",
+			"-e", `tell application "Reminders" to make new reminder with properties {name:(item 1 of argv), body:(item 2 of argv)}`,
+			"-e", "end run",
+			title,
+			link,
+		).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to create reminder: %s: %s", err.Error(), strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+}
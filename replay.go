@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/deanishe/awgo"
+)
+
+// the body of the last itunes api response is kept, so formatting can be
+// worked on (and rendering bugs reproduced from payloads people send in)
+// without going back to the network.
+const lastResponseFile = "last-response.json"
+
+func init() {
+	register(command{
+		name:  "debug",
+		usage: "debug replay [-icons] [file|-]",
+		desc:  "render the last api response (or a saved one) as script filter output again, offline",
+		run:   debugCmd,
+	})
+}
+
+func saveLastResponse(body []byte) {
+	if private() {
+		return
+	}
+	if err := os.MkdirAll(cacheDir(), os.ModePerm); err != nil {
+		return
+	}
+	if err := ioutil.WriteFile(filepath.Join(cacheDir(), lastResponseFile), body, 0600); err != nil {
+		debug("failed to save last response: %s", err.Error())
+	}
+}
+
+func debugCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: debug replay [-icons] [file|-]")
+	}
+	switch args[0] {
+	case "replay":
+		return replay(ctx, args[1:])
+	}
+	return fmt.Errorf("unknown debug command %q", args[0])
+}
+
+// replay runs an itunes api response body through the same rendering as a
+// search: the last one received, or one from a file (- for stdin). icons
+// are left out unless asked for, they would have to be downloaded.
+func replay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("debug replay", flag.ContinueOnError)
+	icons := fs.Bool("icons", false, "show app icons, downloading those not cached")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if !*icons {
+		os.Setenv("ARTWORK", "none")
+	}
+	var (
+		body []byte
+		err  error
+	)
+	switch {
+	case len(args) == 0:
+		body, err = ioutil.ReadFile(filepath.Join(cacheDir(), lastResponseFile))
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no api response saved yet, search for something first")
+		}
+	case args[0] == "-":
+		body, err = ioutil.ReadAll(os.Stdin)
+	default:
+		body, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Results []app `json:"results"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("not an itunes api response: %s", err.Error())
+	}
+	fb := aw.NewFeedback()
+	appendApps(ctx, fb, resp.Results)
+	return emit(fb)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif" // some artwork is a gif
+	"image/jpeg"
+	"image/png"
+)
+
+// shrinkIcon scales artwork larger than size pixels down to size, keeping
+// its format. alfred draws icons at well under 256px, anything bigger only
+// costs disk and decode time. data is returned untouched when it is already
+// small enough or cannot be decoded.
+func shrinkIcon(data []byte, contentType string, size int) ([]byte, string) {
+	if size <= 0 {
+		return data, contentType
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		debug("not resizing undecodable artwork: %s", err.Error())
+		return data, contentType
+	}
+	b := img.Bounds()
+	if b.Dx() <= size && b.Dy() <= size {
+		return data, contentType
+	}
+	w, h := size, size
+	if b.Dx() > b.Dy() {
+		h = size * b.Dy() / b.Dx()
+	} else if b.Dy() > b.Dx() {
+		w = size * b.Dx() / b.Dy()
+	}
+	small := boxResize(img, w, h)
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, small, &jpeg.Options{Quality: 90})
+		contentType = "image/jpeg"
+	} else {
+		err = png.Encode(&buf, small)
+		contentType = "image/png"
+	}
+	if err != nil {
+		debug("failed to encode resized artwork: %s", err.Error())
+		return data, contentType
+	}
+	return buf.Bytes(), contentType
+}
+
+// boxResize downscales src to w×h, averaging every source pixel that falls
+// into each destination pixel. good enough for shrinking, useless for
+// enlarging.
+func boxResize(src image.Image, w, h int) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := b.Min.Y + (y+1)*b.Dy()/h
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := b.Min.X + (x+1)*b.Dx()/w
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// premultiplied, so transparent pixels do not bleed
+					// their color into the edges
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			if n == 0 || a == 0 {
+				continue
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r * 0xff / a),
+				G: uint8(g * 0xff / a),
+				B: uint8(bl * 0xff / a),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
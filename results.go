@@ -0,0 +1,29 @@
+package main
+
+const resultsCachePrefix = "results-"
+
+func resultsCacheKey(term string) string {
+	return resultsCachePrefix + md5hash(term) + ".json"
+}
+
+func storeResults(key string, results []app) {
+	if private() {
+		return
+	}
+	if err := cache().StoreJSON(key, results); err != nil {
+		debug("failed to cache results: %s", err.Error())
+	}
+}
+
+func cachedResults(key string) []app {
+	c := cache()
+	if !c.Exists(key) {
+		return nil
+	}
+	var results []app
+	if err := c.LoadJSON(key, &results); err != nil {
+		debug("failed to load cached results: %s", err.Error())
+		return nil
+	}
+	return results
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+const savedFile = "saved.json"
+
+// saved searches map a name to the query (operators and all) it stands for.
+func loadSaved() (map[string]string, error) {
+	saved := map[string]string{}
+	c := aw.NewCache(dataDir())
+	if !c.Exists(savedFile) {
+		return saved, nil
+	}
+	return saved, c.LoadJSON(savedFile, &saved)
+}
+
+func storeSaved(saved map[string]string) error {
+	return aw.NewCache(dataDir()).StoreJSON(savedFile, saved)
+}
+
+func init() {
+	registerAction(action{
+		name: "save-search",
+		desc: "Save search",
+		run: func(ctx context.Context, arg string) error {
+			return savedCmd(ctx, []string{"add", os.Getenv("saved_name"), arg})
+		},
+	})
+	registerAction(action{
+		name: "delete-saved-search",
+		desc: "Delete saved search",
+		run: func(ctx context.Context, arg string) error {
+			return savedCmd(ctx, []string{"remove", arg})
+		},
+	})
+	register(command{
+		name:  "saved",
+		usage: "saved list|add <name> <query>|remove <name>",
+		desc:  "manage saved searches",
+		run:   savedCmd,
+	})
+}
+
+func savedCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	saved, err := loadSaved()
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		for _, name := range sortedKeys(saved) {
+			fmt.Printf("%s\t%s\n", name, saved[name])
+		}
+		return nil
+	case "add":
+		if len(args) < 3 || strings.TrimSpace(args[1]) == "" {
+			return fmt.Errorf("usage: saved add <name> <query>")
+		}
+		saved[normalizeQuery(args[1])] = strings.Join(args[2:], " ")
+	case "remove":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: saved remove <name>")
+		}
+		delete(saved, normalizeQuery(args[1]))
+	default:
+		return fmt.Errorf("unknown saved command %q", args[0])
+	}
+	return storeSaved(saved)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// savedFeedback lists the saved searches whose name starts with prefix.
+// ↩ or ⇥ puts the saved query into alfred, ⌥ deletes it.
+func savedFeedback(fb *aw.Feedback, prefix string) error {
+	saved, err := loadSaved()
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(saved) {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		it := fb.NewItem(name).
+			Subtitle(saved[name]).
+			Autocomplete(saved[name]).
+			Icon(aw.IconFavorite).
+			Valid(false)
+		it.NewModifier(aw.ModAlt).
+			Valid(true).
+			Arg(name).
+			Subtitle("Delete this saved search").
+			Var("action", "delete-saved-search")
+	}
+	return nil
+}
+
+// saveSearchItem offers to save the rest of the query under name.
+func saveSearchItem(name, query string) *aw.Item {
+	if name == "" || query == "" {
+		return new(aw.Item).
+			Title("Save a search").
+			Subtitle("type save:name followed by the search to save").
+			Icon(aw.IconInfo).
+			Valid(false)
+	}
+	return new(aw.Item).
+		Title(fmt.Sprintf("Save “%s” as “%s”", query, name)).
+		Subtitle("recall it later with saved:"+name).
+		Arg(query).
+		Var("action", "save-search").
+		Var("saved_name", name).
+		Icon(aw.IconFavorite).
+		Valid(true)
+}
+
+// withoutOperator removes the key:value token for key from raw, leaving the
+// rest of the query as typed.
+func withoutOperator(raw, key string) string {
+	var rest []string
+	for _, tok := range strings.Fields(raw) {
+		if k, _, ok := splitOperator(tok); ok && k == key {
+			continue
+		}
+		rest = append(rest, tok)
+	}
+	return strings.Join(rest, " ")
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+func init() {
+	register(command{
+		name:  "search",
+		usage: "search <term>",
+		desc:  "search the mac app store, as script filter json in alfred or a table in a terminal",
+		run:   searchCmd,
+	})
+}
+
+type app struct {
+	ID         int64     `json:"trackId"`
+	Name       string    `json:"trackName"`
+	BundleID   string    `json:"bundleId"`
+	Artwork    string    `json:"artworkUrl512"`
+	URL        string    `json:"trackViewUrl"`
+	Rating     float64   `json:"averageUserRating"`
+	PriceFmt   string    `json:"formattedPrice"`
+	NumRatings int       `json:"userRatingCount"`
+	Developer  string    `json:"artistName"`
+	Seller     string    `json:"sellerName"`
+	AgeRating  string    `json:"contentAdvisoryRating"`
+	Version    string    `json:"version"`
+	Genre      string    `json:"primaryGenreName"`
+	GenreID    int       `json:"primaryGenreId"`
+	Languages  []string  `json:"languageCodesISO2A"`
+	FileSize   string    `json:"fileSizeBytes"`
+	Price      float64   `json:"price"`
+	Currency   string    `json:"currency"`
+	Genres     []string  `json:"genres"`
+	GameCenter bool      `json:"isGameCenterEnabled"`
+	Updated    time.Time `json:"currentVersionReleaseDate"`
+	Kind       string    `json:"kind"`
+	// the current version's share of Rating and NumRatings
+	CurrentRating     float64 `json:"averageUserRatingForCurrentVersion,omitempty"`
+	CurrentNumRatings int     `json:"userRatingCountForCurrentVersion,omitempty"`
+	// only lookups are sure to send these, see enrich
+	Description  string   `json:"description,omitempty"`
+	ReleaseNotes string   `json:"releaseNotes,omitempty"`
+	Screenshots  []string `json:"screenshotUrls,omitempty"`
+	// Countries is filled in by search when more than one storefront is
+	// searched, apple does not send it.
+	Countries []string `json:"countries,omitempty"`
+	// only the amp backend knows these
+	EditorialNote  string   `json:"editorialNote,omitempty"`
+	InAppPurchases []string `json:"inAppPurchases,omitempty"`
+	// Source is the provider a non app store result came from, the rest
+	// are only filled in by some of them
+	Source      string `json:"source,omitempty"`
+	Summary     string `json:"summary,omitempty"`
+	DownloadURL string `json:"downloadUrl,omitempty"`
+	Install     string `json:"install,omitempty"`
+}
+
+// size is the download size in bytes, or 0 when apple did not say.
+func (a app) size() int64 {
+	n, _ := strconv.ParseInt(a.FileSize, 10, 64)
+	return n
+}
+
+func (a app) isGame() bool {
+	if a.Genre == "Games" {
+		return true
+	}
+	for _, g := range a.Genres {
+		if g == "Games" {
+			return true
+		}
+	}
+	return false
+}
+
+// stale reports whether the app has gone long enough without an update that
+// it might be abandoned. STALE_AFTER_DAYS sets the threshold.
+func (a app) stale() bool {
+	if a.Updated.IsZero() {
+		return false
+	}
+	days := configInt("STALE_AFTER_DAYS")
+	return days > 0 && time.Since(a.Updated) > time.Duration(days)*time.Hour*24
+}
+
+func searchCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("search term is required")
+	}
+	if isMagic(args[0]) {
+		return emit(magicFeedback(args[0]))
+	}
+	fb := aw.NewFeedback()
+	if it := crashItem(crashNoticeWindow); it != nil {
+		fb.Items = append(fb.Items, it)
+	}
+	for _, err := range configErrors() {
+		fb.Items = append(fb.Items, configErrorItem(err))
+	}
+	if strings.TrimSpace(args[0]) == "" {
+		if firstRun() {
+			fb.Items = append(fb.Items, welcomeItem())
+		}
+		if err := savedFeedback(fb, ""); err != nil {
+			return err
+		}
+		if len(fb.Items) == 0 {
+			fb.NewItem("Search the App Store").
+				Subtitle("start typing to search, save:name <query> saves a search for later").
+				Icon(aw.IconInfo).
+				Valid(false)
+		}
+		return emit(fb)
+	}
+	q := parseQuery(args[0])
+	if q.saveAs != nil {
+		fb.Items = append(fb.Items, saveSearchItem(*q.saveAs, withoutOperator(args[0], "save")))
+		return emit(fb)
+	}
+	if q.setup != nil {
+		setupFeedback(fb, *q.setup, strings.Join(q.terms, " "))
+		return emit(fb)
+	}
+	if q.mine != nil {
+		mineFeedback(ctx, fb, *q.mine, q.terms)
+		return emit(fb)
+	}
+	if q.saved != nil {
+		if err := savedFeedback(fb, *q.saved); err != nil {
+			return err
+		}
+		return emit(fb)
+	}
+	if q.trending {
+		if err := trendingFeedback(ctx, fb); err != nil {
+			return err
+		}
+		return emit(fb)
+	}
+	if q.desc != nil {
+		descFeedback(ctx, fb, strings.TrimSpace(*q.desc+" "+strings.Join(q.terms, " ")))
+		return emit(fb)
+	}
+	if q.recent {
+		recentFeedback(ctx, fb, strings.Join(q.terms, " "))
+		return emit(fb)
+	}
+	if q.pins {
+		if err := pinsFeedback(ctx, fb); err != nil {
+			return err
+		}
+		return emit(fb)
+	}
+	if q.games != nil {
+		if err := gamesFeedback(ctx, fb, *q.games, q.terms); err != nil {
+			return err
+		}
+		return emit(fb)
+	}
+	if q.privacy != 0 {
+		privacy, err := privacyFeedback(ctx, q.privacy)
+		if err != nil {
+			return err
+		}
+		fb.Items = append(fb.Items, privacy.Items...)
+		return emit(fb)
+	}
+	if q.compare != 0 {
+		if err := compareFeedback(ctx, fb, q.compare); err != nil {
+			return err
+		}
+		return emit(fb)
+	}
+	if q.id != 0 {
+		detail, err := detailFeedback(ctx, q.id)
+		if err != nil {
+			return err
+		}
+		fb.Items = append(fb.Items, detail.Items...)
+		return emit(fb)
+	}
+	results, err := search(ctx, q)
+	if isRateLimit(err) {
+		fb.Items = append(fb.Items, limitItem(err, len(results) > 0))
+	} else if err == errOffline {
+		fb.Items = append(fb.Items, offlineItem(len(results) > 0))
+	} else if err != nil && len(results) > 0 {
+		fb.Items = append(fb.Items, partialItem(err))
+	} else if err != nil {
+		return err
+	}
+	if err == nil && len(results) > 0 {
+		recordQuery(normalizeQuery(args[0]))
+		defer maybePrefetch()
+	}
+	full := len(results) >= q.limit()
+	results = q.filter(results)
+	if err == nil && len(q.terms) > 0 && poorMatches(results, q.term()) {
+		if suggestion := didYouMean(ctx, q.term()); suggestion != "" {
+			fb.Items = append(fb.Items, didYouMeanItem(args[0], q.term(), suggestion))
+		}
+	}
+	rerank(results)
+	results = withPins(results, q.term())
+	results, more := q.paginate(results, full)
+	if config("RESULTS_HEADER") == "yes" {
+		fb.Items = append(fb.Items, headerItem(args[0], q, len(results), err))
+	}
+	if q.page > 1 {
+		fb.Items = append(fb.Items, pageItem(args[0], q.page-1, "Previous page"))
+	}
+	if wantsEnrichment() {
+		enrich(ctx, results)
+	}
+	appendApps(ctx, fb, results)
+	if more {
+		fb.Items = append(fb.Items, pageItem(args[0], q.page+1, "Next page"))
+	}
+	if term := q.term(); term != "" {
+		fb.Items = append(fb.Items, storeSearchItem(term))
+	}
+	return emit(fb)
+}
+
+// appendApps adds an item for each app that is not blocklisted to fb, with
+// its artwork as the icon.
+func appendApps(ctx context.Context, fb *aw.Feedback, results []app) {
+	results = unblocked(results)
+	offset := len(fb.Items)
+	images := make([]string, len(results))
+	for i, res := range results {
+		fb.Items = append(fb.Items, appItem(res))
+		images[i] = res.Artwork
+	}
+	icons := downloadAllImages(ctx, downloadConcurrency(), images)
+	for i := range icons {
+		if ip, ok := providers[results[i].Source].(iconProvider); ok && results[i].Artwork == "" {
+			icons[i] = ip.icon()
+		}
+		icons[i] = badgedIcon(icons[i], results[i])
+		fb.Items[offset+i] = fb.Items[offset+i].Icon(icons[i])
+	}
+}
+
+// resultsTTL is how long a search is answered from the results cache without
+// asking apple again.
+const resultsTTL = time.Hour
+
+var errOffline = errors.New("the app store could not be reached")
+
+// search runs q against the provider it asks for. repeat searches within
+// resultsTTL are answered from cache. when requests are held back it returns
+// errRateLimited or errThrottled, and when the store cannot be reached
+// errOffline, both along with the best results available locally. when
+// only some of the provider's requests failed, whatever the error, the
+// results that did come back are returned with it and not cached.
+func search(ctx context.Context, q query) ([]app, error) {
+	key := q.cacheKey()
+	if c := cache(); c.Exists(key) && !c.Expired(key, resultsTTL) {
+		debug("serving cached results for %q", q.term())
+		return cachedResults(key), nil
+	}
+	results, err := q.provider().search(ctx, q)
+	if _, ok := err.(*url.Error); ok {
+		debug("request failed, falling back to local results: %s", err.Error())
+		err = errOffline
+	}
+	if err != nil && len(results) > 0 {
+		debug("showing partial results: %s", err.Error())
+		return results, err
+	} else if isRateLimit(err) || err == errOffline {
+		return localResults(key, q), err
+	} else if err != nil {
+		return nil, err
+	}
+	storeResults(key, results)
+	indexApps(results)
+	return results, nil
+}
+
+const appStoreProvider = "appstore"
+
+func init() {
+	registerProvider(appStore{})
+}
+
+// appStore searches the itunes search api, on every configured platform and
+// storefront at once.
+type appStore struct{}
+
+func (appStore) name() string  { return appStoreProvider }
+func (appStore) label() string { return "App Store" }
+
+func (appStore) search(ctx context.Context, q query) ([]app, error) {
+	type request struct{ country, entity string }
+	var requests []request
+	for _, country := range searchCountries() {
+		for _, entity := range searchEntities() {
+			requests = append(requests, request{country, entity})
+		}
+	}
+	multiCountry := len(requests) > len(searchEntities())
+	lists := make([][]app, len(requests))
+	errs := make([]error, len(requests))
+	var wg sync.WaitGroup
+	for i, r := range requests {
+		wg.Add(1)
+		go func(i int, r request) {
+			defer wg.Done()
+			lists[i], errs[i] = searchBackend(ctx, r.country, r.entity, q.term(), q.attribute(), q.limit())
+			if multiCountry {
+				for j := range lists[i] {
+					lists[i][j].Countries = []string{r.country}
+				}
+			}
+		}(i, r)
+	}
+	wg.Wait()
+	// one request failing only loses its own results. the error still goes
+	// back with them, so they are shown but not cached.
+	var failed error
+	for i, err := range errs {
+		if err != nil {
+			debug("search in %s failed: %s", requests[i].country, err.Error())
+			failed = err
+		}
+	}
+	return mergeResults(lists), failed
+}
+
+// localResults is the best we can do without the network: the last results
+// for this exact search, or failing that whatever the index has seen (which
+// only ever holds app store apps).
+func localResults(key string, q query) []app {
+	if results := cachedResults(key); len(results) > 0 {
+		return results
+	}
+	if q.provider().name() != appStoreProvider {
+		return nil
+	}
+	return searchIndex(q.term(), q.limit())
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc answers the workflow's requests without the network.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestSearchPartialResults(t *testing.T) {
+	useTempCache(t)
+	dir, err := ioutil.TempDir("", "search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	setenv(t, "alfred_workflow_data", dir)
+	setenv(t, "API_RATE_LIMIT", "0")
+	setenv(t, "PLATFORMS", "mac")
+	setenv(t, "COUNTRY", "us")
+	setenv(t, "EXTRA_COUNTRIES", "gb")
+	setenv(t, "BACKEND", "itunes")
+	setenv(t, "CACHE_BACKEND", "file")
+
+	defer func(old http.RoundTripper) { client.Transport = old }(client.Transport)
+	client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("country") == "gb" {
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+				Request:    req,
+			}, nil
+		}
+		body := `{"results":[{"trackId":1,"trackName":"Things","kind":"mac-software"}]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+
+	q := parseQuery("things")
+	results, err := search(context.Background(), q)
+	if err == nil {
+		t.Error("search() with a failing storefront returned no error")
+	}
+	if len(results) != 1 || results[0].ID != 1 {
+		t.Errorf("search() = %v, want the results of the working storefront", results)
+	}
+	if cache().Exists(q.cacheKey()) {
+		t.Error("partial results were cached")
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// credentials can live in the login keychain instead of workflow variables,
+// which alfred keeps in plain text (and exports along with the workflow
+// unless told not to). they are generic passwords with the bundle id as the
+// service and the setting's key as the account.
+//
+// the security tool prints values that are not plain printable text as hex,
+// so every secret is kept on one line: .p8 keys are stored as the base64 of
+// the key, without the pem armor.
+
+// secretKeys are the names secrets can be stored under. the ones that are
+// also settings are read from the keychain when the setting is left empty.
+var secretKeys = []string{"CONNECT_ISSUER_ID", "CONNECT_KEY_ID", "CONNECT_KEY", "CRASH_REPORT_DSN"}
+
+// ampTokenSecret holds the amp bearer token, see ampToken.
+const ampTokenSecret = "AMP_TOKEN"
+
+var secretCache sync.Map
+
+func init() {
+	register(command{
+		name:  "secret",
+		usage: "secret set|delete|list [key] [value]",
+		desc:  "store credentials in the macos keychain instead of workflow variables",
+		run:   secretCmd,
+	})
+}
+
+func isSecret(key string) bool {
+	return containsString(secretKeys, key)
+}
+
+// secret reads key from the keychain, "" when it is not there. lookups are
+// remembered for the life of the process, config asks for the same ones a
+// lot.
+func secret(key string) string {
+	if v, ok := secretCache.Load(key); ok {
+		return v.(string)
+	}
+	out, err := exec.Command("security", "find-generic-password", "-s", bundleID, "-a", key, "-w").Output()
+	v := ""
+	if err == nil {
+		v = strings.TrimRight(string(out), "\n")
+	} else if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 44 {
+		// 44 is "the specified item could not be found"
+		debug("failed to read %s from the keychain: %s", key, err.Error())
+	}
+	secretCache.Store(key, v)
+	return v
+}
+
+// setSecret stores value under key, replacing what was there. the value is
+// handed to security on stdin rather than as an argument, where other users
+// could see it in the process list.
+func setSecret(ctx context.Context, key, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("secrets have to fit on one line")
+	}
+	cmd := exec.CommandContext(ctx, "security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf(
+		"add-generic-password -U -s %s -a %s -l %s -X %s\n",
+		bundleID, key, binaryName+"-"+strings.ToLower(key), hex.EncodeToString([]byte(value)),
+	))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to store %s in the keychain: %s: %s", key, err.Error(), strings.TrimSpace(string(out)))
+	}
+	secretCache.Store(key, value)
+	return nil
+}
+
+func deleteSecret(ctx context.Context, key string) error {
+	secretCache.Delete(key)
+	out, err := exec.CommandContext(ctx, "security", "delete-generic-password", "-s", bundleID, "-a", key).CombinedOutput()
+	if e, ok := err.(*exec.ExitError); ok && e.ExitCode() == 44 {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to delete %s from the keychain: %s: %s", key, err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func secretCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch args[0] {
+	case "list":
+		for _, key := range secretKeys {
+			state := "not set"
+			if secret(key) != "" {
+				state = "in keychain"
+			}
+			if os.Getenv(key) != "" {
+				state += ", overridden by workflow variable"
+			}
+			fmt.Printf("%-20s %s\n", key, state)
+		}
+		return nil
+	case "set":
+		if len(args) < 2 || !isSecret(args[1]) {
+			return fmt.Errorf("usage: secret set <%s> [value]", strings.Join(secretKeys, "|"))
+		}
+		value, err := secretValue(args[1], args[2:])
+		if err != nil {
+			return err
+		}
+		return setSecret(ctx, args[1], value)
+	case "delete":
+		if len(args) < 2 || !isSecret(args[1]) {
+			return fmt.Errorf("usage: secret delete <%s>", strings.Join(secretKeys, "|"))
+		}
+		return deleteSecret(ctx, args[1])
+	}
+	return fmt.Errorf("unknown secret command %q", args[0])
+}
+
+// secretValue is the value to store for key: the argument if one was given,
+// otherwise the first line of stdin, so it stays out of shell history.
+// CONNECT_KEY takes the path of the .p8 file instead.
+func secretValue(key string, args []string) (string, error) {
+	var value string
+	if len(args) > 0 {
+		value = args[0]
+	} else {
+		if isTerminal(os.Stdin) {
+			fmt.Fprintf(os.Stderr, "%s: ", key)
+		}
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("no value given for %s", key)
+		}
+		value = line
+	}
+	value = strings.TrimSpace(value)
+	if key != "CONNECT_KEY" {
+		if s := lookupSettingOK(key); s != nil && s.check != nil {
+			if err := s.check(value); err != nil {
+				return "", fmt.Errorf("%s: %s", s.label, err.Error())
+			}
+		}
+		return value, nil
+	}
+	data, err := ioutil.ReadFile(value)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("%s is not a .p8 key file", value)
+	}
+	if _, err := parseConnectKey(block.Bytes); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(block.Bytes), nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+// setapp has no public search api. its sitemap lists a page for every app in
+// the subscription though, which is enough to search by name and to tell
+// whether an app store result is also on setapp.
+const (
+	setappSitemapURL = "https://setapp.com/sitemap.xml"
+	setappFile       = "setapp.json"
+	setappMaxAge     = time.Hour * 24
+)
+
+var setappAppPage = regexp.MustCompile(`^https://setapp\.com/apps/([a-z0-9-]+)/?$`)
+
+type setappApp struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+func init() {
+	registerProvider(setapp{})
+	registerRefreshJob("setapp", func(ctx context.Context) error {
+		if !providerEnabled("setapp") {
+			return nil
+		}
+		catalog, err := fetchSetappCatalog(ctx)
+		if err != nil {
+			return err
+		}
+		return cache().StoreJSON(setappFile, catalog)
+	})
+}
+
+type setapp struct{}
+
+func (setapp) name() string  { return "setapp" }
+func (setapp) label() string { return "Setapp" }
+
+// icon is the setapp app's own icon, when it is installed.
+func (setapp) icon() *aw.Icon {
+	return &aw.Icon{Value: "/Applications/Setapp.app", Type: aw.IconTypeFileIcon}
+}
+
+func (setapp) search(ctx context.Context, q query) ([]app, error) {
+	catalog, err := setappCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(q.term())
+	var results []app
+	for _, sa := range catalog {
+		if len(words) > 0 && containsAllWords(sa.Name, words) {
+			results = append(results, sa.toApp())
+		}
+	}
+	return results, nil
+}
+
+func (sa setappApp) toApp() app {
+	return app{
+		ID:       catalogID("setapp", sa.URL),
+		Name:     sa.Name,
+		URL:      sa.URL,
+		PriceFmt: "included with Setapp",
+		Source:   "setapp",
+	}
+}
+
+// catalogID makes up a stable id for an app from a catalog without numeric
+// ids. it is negative, so it never collides with an app store track id.
+func catalogID(source, key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(source + ":" + key))
+	return -int64(h.Sum64() >> 1)
+}
+
+func setappCatalog(ctx context.Context) ([]setappApp, error) {
+	var catalog []setappApp
+	err := cache().LoadOrStoreJSON(setappFile, setappMaxAge, func() (interface{}, error) {
+		return fetchSetappCatalog(ctx)
+	}, &catalog)
+	return catalog, err
+}
+
+func fetchSetappCatalog(ctx context.Context) ([]setappApp, error) {
+	req, err := http.NewRequest("GET", setappSitemapURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	debug("sending request: %s %s", req.Method, req.URL.String())
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	var sitemap struct {
+		URLs []struct {
+			Loc string `xml:"loc"`
+		} `xml:"url"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&sitemap); err != nil {
+		return nil, err
+	}
+	var catalog []setappApp
+	for _, u := range sitemap.URLs {
+		m := setappAppPage.FindStringSubmatch(strings.TrimSpace(u.Loc))
+		if m == nil {
+			continue
+		}
+		catalog = append(catalog, setappApp{Name: slugTitle(m[1]), URL: m[0]})
+	}
+	return catalog, nil
+}
+
+// slugTitle turns "bartender-4" into "Bartender 4".
+func slugTitle(slug string) string {
+	words := strings.Split(slug, "-")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+var (
+	setappNamesOnce sync.Once
+	setappNames     map[string]bool
+)
+
+// onSetapp reports whether an app with res's name is part of setapp, when
+// the setapp provider is enabled. it only looks at a catalog that is already
+// cached (see the setapp refresh job), never fetching one, so annotating
+// results costs nothing.
+func onSetapp(res app) bool {
+	if !providerEnabled("setapp") {
+		return false
+	}
+	setappNamesOnce.Do(func() {
+		setappNames = map[string]bool{}
+		var catalog []setappApp
+		c := cache()
+		if !c.Exists(setappFile) || c.LoadJSON(setappFile, &catalog) != nil {
+			return
+		}
+		for _, sa := range catalog {
+			setappNames[normalizeQuery(sa.Name)] = true
+		}
+	})
+	return setappNames[baseName(res.Name)]
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+)
+
+func init() {
+	registerAction(action{
+		name: "share",
+		desc: "Recommend this app to someone",
+		arg:  func(res app) string { return res.URL },
+		subtitle: func(res app) string {
+			if config("SHARE_VIA") == "messages" {
+				return "Share in Messages"
+			}
+			return "Share by email"
+		},
+		run: share,
+	})
+}
+
+// share opens a message with the app's name (from the app_name variable)
+// and store link filled in, in Mail or Messages depending on SHARE_VIA.
+func share(ctx context.Context, link string) error {
+	name := os.Getenv("app_name")
+	body := name + " " + link
+	escape := func(s string) string { return encodeSpaces(url.QueryEscape(s)) }
+	if config("SHARE_VIA") == "messages" {
+		return openURL(ctx, "sms:&body="+escape(body))
+	}
+	return openURL(ctx, "mailto:?subject="+escape(name)+"&body="+escape(body))
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(command{
+		name:  "shortcuts",
+		usage: "shortcuts [key=value...]",
+		desc:  "search or look up apps as plain json, for shortcuts' run shell script. with no arguments a json object is read from stdin",
+		run:   shortcutsCmd,
+	})
+}
+
+// shortcutsRequest is what a shortcut asks for, as key=value arguments or a
+// json object on stdin (shortcuts passes its input that way).
+type shortcutsRequest struct {
+	Action   string
+	Term     string
+	ID       int64
+	Country  string
+	Limit    int
+	Platform string
+}
+
+// shortcutsApp is an app as shortcuts sees it: flat, with plain names, and
+// links for each way to open it.
+type shortcutsApp struct {
+	ID        int64   `json:"id"`
+	Name      string  `json:"name"`
+	Developer string  `json:"developer"`
+	BundleID  string  `json:"bundleId"`
+	Price     float64 `json:"price"`
+	PriceText string  `json:"priceText"`
+	Currency  string  `json:"currency"`
+	Rating    float64 `json:"rating"`
+	Ratings   int     `json:"ratings"`
+	Version   string  `json:"version"`
+	Genre     string  `json:"genre"`
+	Platform  string  `json:"platform"`
+	Artwork   string  `json:"artwork"`
+	URL       string  `json:"url"`
+	StoreURL  string  `json:"storeUrl"`
+	AlfredURL string  `json:"alfredUrl"`
+}
+
+// alfredURL opens the workflow's search for query through alfred's url
+// handler, which the search external trigger is exposed to.
+func alfredURL(query string) string {
+	return "alfred://runtrigger/" + bundleID + "/search/?" + url.Values{"argument": {query}}.Encode()
+}
+
+func toShortcutsApp(res app) shortcutsApp {
+	return shortcutsApp{
+		ID:        res.ID,
+		Name:      res.Name,
+		Developer: res.Developer,
+		BundleID:  res.BundleID,
+		Price:     res.Price,
+		PriceText: res.PriceFmt,
+		Currency:  res.Currency,
+		Rating:    res.Rating,
+		Ratings:   res.NumRatings,
+		Version:   res.Version,
+		Genre:     res.Genre,
+		Platform:  platformLabel(res),
+		Artwork:   res.Artwork,
+		URL:       res.URL,
+		StoreURL:  storeURL(res.ID),
+		AlfredURL: alfredURL(fmt.Sprintf("id:%d", res.ID)),
+	}
+}
+
+func parseShortcutsRequest(args []string) (shortcutsRequest, error) {
+	var req shortcutsRequest
+	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return req, err
+		}
+		// shortcuts sends numbers as numbers or text depending on where
+		// they came from, accept both
+		var raw map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return req, fmt.Errorf("expected a json object on stdin: %s", err.Error())
+		}
+		args = nil
+		for k, v := range raw {
+			args = append(args, fmt.Sprintf("%s=%v", k, v))
+		}
+	}
+	for _, tok := range args {
+		i := strings.IndexByte(tok, '=')
+		if i <= 0 {
+			return req, fmt.Errorf("expected key=value, got %q", tok)
+		}
+		k, v := strings.ToLower(tok[:i]), tok[i+1:]
+		switch k {
+		case "action":
+			req.Action = v
+		case "term":
+			req.Term = v
+		case "id":
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return req, fmt.Errorf("%q is not an app id", v)
+			}
+			req.ID = id
+		case "country":
+			req.Country = v
+		case "limit":
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return req, fmt.Errorf("%q is not a number", v)
+			}
+			req.Limit = n
+		case "platform":
+			req.Platform = v
+		default:
+			return req, fmt.Errorf("unknown key %q", k)
+		}
+	}
+	if req.Action == "" {
+		req.Action = "search"
+		if req.ID != 0 {
+			req.Action = "lookup"
+		}
+	}
+	return req, nil
+}
+
+// shortcutsCmd prints a json list of apps and nothing else, which shortcuts
+// turns into a list of dictionaries. errors go to stderr with a non-zero
+// exit, which stops the shortcut.
+func shortcutsCmd(ctx context.Context, args []string) error {
+	req, err := parseShortcutsRequest(args)
+	if err != nil {
+		return err
+	}
+	// overrides are passed on the same way alfred passes settings
+	for key, v := range map[string]string{"COUNTRY": req.Country, "PLATFORMS": req.Platform} {
+		if v != "" {
+			os.Setenv(key, v)
+		}
+	}
+	if req.Limit > 0 {
+		os.Setenv("RESULT_LIMIT", strconv.Itoa(req.Limit))
+	}
+	var results []app
+	switch req.Action {
+	case "search":
+		if strings.TrimSpace(req.Term) == "" {
+			return fmt.Errorf("action=search needs a term")
+		}
+		q := parseQuery(req.Term)
+		results, err = search(ctx, q)
+		if err != nil && !isRateLimit(err) && err != errOffline && len(results) == 0 {
+			return err
+		}
+		results = unblocked(q.filter(results))
+		if len(results) > q.limit() {
+			results = results[:q.limit()]
+		}
+	case "lookup":
+		if req.ID == 0 {
+			return fmt.Errorf("action=lookup needs an id")
+		}
+		res, err := lookup(ctx, req.ID)
+		if err != nil {
+			return err
+		}
+		if res != nil {
+			results = []app{*res}
+		}
+	default:
+		return fmt.Errorf("unknown action %q, expected search or lookup", req.Action)
+	}
+	out := make([]shortcutsApp, len(results))
+	for i, res := range results {
+		out[i] = toShortcutsApp(res)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const (
+	// storefrontsTTL is how long the storefronts an app is sold in are
+	// remembered, found or not
+	storefrontsTTL = time.Hour * 24
+	// storefrontConcurrency keeps the hint lookups from taking every token
+	// in the bucket at once
+	storefrontConcurrency = 2
+)
+
+// hintStorefronts are tried when an app cannot be found in the configured
+// storefront, to tell the user where it is sold instead. kept short, every
+// one of them is a request.
+var hintStorefronts = []string{"us", "gb", "ca", "au", "de", "fr", "jp", "cn"}
+
+const storefrontsCachePrefix = "storefronts-"
+
+// storefronts is what availableIn found out about an app.
+type storefronts struct {
+	Countries []string `json:"countries"`
+	App       *app     `json:"app"`
+}
+
+// availableIn looks id up in the hint storefronts (and any EXTRA_COUNTRIES)
+// other than COUNTRY, returning the countries that sell it and the app as
+// the first of them knows it. the answer is cached, misses included, unless
+// it was cut short by the rate limit.
+func availableIn(ctx context.Context, id int64) ([]string, *app) {
+	home := strings.ToLower(config("COUNTRY"))
+	key := fmt.Sprintf("%s%s-%d.json", storefrontsCachePrefix, home, id)
+	c := cache()
+	var sf storefronts
+	if c.Exists(key) && !c.Expired(key, storefrontsTTL) {
+		if err := c.LoadJSON(key, &sf); err == nil {
+			return sf.Countries, sf.App
+		}
+	}
+	var countries []string
+	for _, c := range append(searchCountries()[1:], hintStorefronts...) {
+		if c != home && !containsString(countries, c) {
+			countries = append(countries, c)
+		}
+	}
+	found := make([]*app, len(countries))
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		limited bool
+		sem     = make(chan struct{}, storefrontConcurrency)
+	)
+	for i, country := range countries {
+		wg.Add(1)
+		go func(i int, country string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			mu.Lock()
+			stop := limited
+			mu.Unlock()
+			if stop {
+				return
+			}
+			results, err := lookupBackend(ctx, country, []string{fmt.Sprint(id)})
+			if isRateLimit(err) {
+				// the rest would only be turned away as well
+				mu.Lock()
+				limited = true
+				mu.Unlock()
+				return
+			} else if err != nil {
+				debug("storefront %s lookup failed: %s", country, err.Error())
+				return
+			}
+			if len(results) > 0 {
+				found[i] = &results[0]
+			}
+		}(i, country)
+	}
+	wg.Wait()
+	for i, r := range found {
+		if r == nil {
+			continue
+		}
+		sf.Countries = append(sf.Countries, countries[i])
+		if sf.App == nil {
+			sf.App = r
+		}
+	}
+	// in private mode it would say which apps were looked at
+	if limited {
+		debug("rate limited, storefronts for %d are incomplete", id)
+	} else if !private() {
+		if err := c.StoreJSON(key, sf); err != nil {
+			debug("failed to cache storefronts for %d: %s", id, err.Error())
+		}
+	}
+	return sf.Countries, sf.App
+}
+
+// storeLinkID is the track id in an app store link, which is how a pasted
+// link (apps.apple.com/us/app/name/id123) is treated like id:123.
+func storeLinkID(tok string) (int64, bool) {
+	u, err := url.Parse(tok)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http" && u.Scheme != "itms-apps" && u.Scheme != "macappstore" && u.Scheme != "macappstores") {
+		return 0, false
+	}
+	if host := strings.ToLower(u.Host); host != "apps.apple.com" && host != "itunes.apple.com" {
+		return 0, false
+	}
+	m := storeURLID.FindStringSubmatch(u.Path)
+	if m == nil {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(m[1], 10, 64)
+	return id, err == nil
+}
+
+// storefrontHintItem explains that an app exists, just not in COUNTRY.
+// actioning it opens the app's page in the first storefront that sells it.
+func storefrontHintItem(res app, countries []string) *aw.Item {
+	return new(aw.Item).
+		Title(fmt.Sprintf("%s is not available in your storefront (%s)", res.Name, strings.ToUpper(config("COUNTRY")))).
+		Subtitle(fmt.Sprintf("available in %s, ↩ to open it in the %s store", strings.ToUpper(strings.Join(countries, ", ")), strings.ToUpper(countries[0]))).
+		Arg(res.URL).
+		Valid(true).
+		IsFile(false).
+		Icon(aw.IconInfo)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+const hintsURL = "https://search.itunes.apple.com/WebObjects/MZSearchHints.woa/wa/hints"
+
+// poorMatches reports whether none of results has any word of term in its
+// name, which is what a misspelled search usually looks like.
+func poorMatches(results []app, term string) bool {
+	for _, res := range results {
+		name := normalizeQuery(res.Name)
+		for _, w := range strings.Fields(term) {
+			if strings.Contains(name, w) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// didYouMean looks for a likely correction of term among the store's search
+// hints, the user's own past searches and what is trending, returning "" if
+// nothing is close enough.
+func didYouMean(ctx context.Context, term string) string {
+	candidates := topQueries(100)
+	if hints, err := searchHints(ctx, term); err != nil {
+		debug("failed to get search hints: %s", err.Error())
+	} else {
+		candidates = append(candidates, hints...)
+	}
+	if trending, err := trendingSearches(ctx); err == nil {
+		candidates = append(candidates, trending...)
+	}
+	best, bestDist := "", len(term)/4+1
+	for _, c := range candidates {
+		c = normalizeQuery(c)
+		if c == term {
+			continue
+		}
+		if d := editDistance(term, c); d > 0 && d <= bestDist && (best == "" || d < bestDist) {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// searchHints asks the store for the completions it would offer while term
+// is typed into its search field.
+func searchHints(ctx context.Context, term string) ([]string, error) {
+	params := url.Values{}
+	params.Set("clientApplication", "Software")
+	params.Set("term", term)
+	req, err := http.NewRequest("GET", hintsURL+"?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	debug("sending request: %s %s", req.Method, req.URL.String())
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	v, err := decodePlist(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	root, _ := v.(map[string]interface{})
+	list, _ := root["hints"].([]interface{})
+	var hints []string
+	for _, h := range list {
+		entry, _ := h.(map[string]interface{})
+		if t, ok := entry["term"].(string); ok && t != "" {
+			hints = append(hints, t)
+		}
+	}
+	return hints, nil
+}
+
+// editDistance is the levenshtein distance between a and b, in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// didYouMeanItem offers the corrected search, keeping any operators the
+// query had.
+func didYouMeanItem(raw, term, suggestion string) *aw.Item {
+	query := suggestion
+	if normalized := normalizeQuery(raw); strings.Contains(normalized, term) {
+		query = strings.Replace(normalized, term, suggestion, 1)
+	}
+	return new(aw.Item).
+		Title(fmt.Sprintf("Did you mean “%s”?", suggestion)).
+		Subtitle("↩ or ⇥ to search for it instead").
+		Autocomplete(query).
+		Icon(aw.IconHelp).
+		Valid(false)
+}
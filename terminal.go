@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// forceJSON makes emit print script filter json even outside alfred, for
+// callers that promise it (see trigger).
+var forceJSON bool
+
+// inAlfred reports whether alfred started us. it sets a handful of
+// alfred_* variables for every script it runs.
+func inAlfred() bool {
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "alfred_") {
+			return true
+		}
+	}
+	return false
+}
+
+// emit prints v, a script filter response, for alfred. run from a shell it
+// prints the items as a table instead.
+func emit(v interface{}) error {
+	defer timed("encode")()
+	if config("SHOW_TIMING") == "yes" {
+		v = withTiming(v)
+	}
+	if forceJSON || inAlfred() {
+		return json.NewEncoder(os.Stdout).Encode(v)
+	}
+	// the feedback's fields are unexported, so go through the json alfred
+	// would have got
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var fb struct {
+		Items []struct {
+			Title    string `json:"title"`
+			Subtitle string `json:"subtitle"`
+			Arg      string `json:"arg"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &fb); err != nil {
+		return err
+	}
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	style := func(code, s string) string {
+		if !color || s == "" {
+			return s
+		}
+		return "\x1b[" + code + "m" + s + "\x1b[0m"
+	}
+	width := 0
+	for _, it := range fb.Items {
+		if n := utf8.RuneCountInString(it.Title); n > width {
+			width = n
+		}
+	}
+	if width > 40 {
+		width = 40
+	}
+	for i, it := range fb.Items {
+		title := truncate(it.Title, width)
+		pad := strings.Repeat(" ", width-utf8.RuneCountInString(title))
+		fmt.Fprintf(os.Stdout, "%s  %s%s  %s\n", style("2", fmt.Sprintf("%2d", i+1)), style("1", title), pad, it.Subtitle)
+		if it.Arg != "" {
+			fmt.Fprintf(os.Stdout, "    %s\n", style("36", it.Arg))
+		}
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// truncate shortens s to n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// started is as close to process start as the workflow can measure.
+var started = time.Now()
+
+// phaseOrder is the order a search goes through the pipeline, timings are
+// logged in it.
+var phaseOrder = []string{"api", "decode", "icons", "encode"}
+
+// phases adds up how long each phase of the pipeline took. phases that run
+// concurrently (one api call per storefront) add up to more than the wall
+// time they took.
+var phases = struct {
+	sync.Mutex
+	took  map[string]time.Duration
+	count map[string]int
+}{
+	took:  map[string]time.Duration{},
+	count: map[string]int{},
+}
+
+// timed starts timing a run of phase, call the returned func when it is over:
+//
+//	defer timed("api")()
+func timed(phase string) func() {
+	start := time.Now()
+	return func() {
+		phases.Lock()
+		defer phases.Unlock()
+		phases.took[phase] += time.Since(start)
+		phases.count[phase]++
+	}
+}
+
+// logTimings writes how long each phase took to the debug output.
+func logTimings() {
+	phases.Lock()
+	defer phases.Unlock()
+	var parts []string
+	for _, phase := range phaseOrder {
+		n := phases.count[phase]
+		if n == 0 {
+			continue
+		}
+		part := phase + " " + formatMillis(phases.took[phase])
+		if n > 1 {
+			part += fmt.Sprintf(" (%d runs)", n)
+		}
+		parts = append(parts, part)
+	}
+	parts = append(parts, "total "+formatMillis(time.Since(started)))
+	debug("timings: %s", strings.Join(parts, ", "))
+}
+
+func formatMillis(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
+// withTiming adds the time taken so far to the first item's subtitle of v, a
+// script filter response. the items' fields are unexported, so this edits
+// the json alfred would get.
+func withTiming(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var fb map[string]interface{}
+	if err := json.Unmarshal(data, &fb); err != nil {
+		return v
+	}
+	items, _ := fb["items"].([]interface{})
+	if len(items) == 0 {
+		return v
+	}
+	first, ok := items[0].(map[string]interface{})
+	if !ok {
+		return v
+	}
+	took := formatMillis(time.Since(started))
+	if sub, _ := first["subtitle"].(string); sub != "" {
+		first["subtitle"] = sub + " · " + took
+	} else {
+		first["subtitle"] = took
+	}
+	return fb
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	transportOnce sync.Once
+	transport     http.RoundTripper
+)
+
+// baseTransport is what every request goes out on: the default transport,
+// trusting the certificates in CA_BUNDLE on top of the system roots, for
+// machines behind a tls intercepting proxy.
+func baseTransport() http.RoundTripper {
+	transportOnce.Do(func() {
+		transport = http.DefaultTransport
+		if config("CA_BUNDLE") == "" {
+			return
+		}
+		pool, err := caPool(config("CA_BUNDLE"))
+		if err != nil {
+			debug("ignoring CA_BUNDLE: %s", err.Error())
+			return
+		}
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+		transport = t
+	})
+	return transport
+}
+
+// caPool is the system roots plus the pem certificates in filename.
+func caPool(filename string) (*x509.CertPool, error) {
+	if strings.HasPrefix(filename, "~/") {
+		filename = filepath.Join(homeDir(), filename[2:])
+	}
+	pem, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no pem certificates found in %s", filename)
+	}
+	return pool, nil
+}
+
+func checkCABundle(v string) error {
+	if v == "" {
+		return nil
+	}
+	_, err := caPool(v)
+	return err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// with TRACE=1 every http request is written to trace.log in the cache
+// dir: url, status, timing, headers and the start of the response body,
+// enough to make sense of connectivity problems on someone else's machine.
+const (
+	traceFile = "trace.log"
+	// maxTraceSize is where the log starts over, it is for the problem at
+	// hand and not an archive
+	maxTraceSize = 4 << 20
+	// traceBodyBytes is how much of each response body is logged
+	traceBodyBytes = 1024
+)
+
+var traceMu sync.Mutex
+
+// tracing reports whether TRACE is on. PRIVATE wins, the log would have
+// every search term in its urls.
+func tracing() bool {
+	return config("TRACE") == "1" && !private()
+}
+
+// traceRoundTrip sends req on rt, logging the exchange.
+func traceRoundTrip(rt http.RoundTripper, req *http.Request) (*http.Response, error) {
+	var b strings.Builder
+	start := time.Now()
+	fmt.Fprintf(&b, "%s %s %s\n", start.Format(time.RFC3339Nano), req.Method, req.URL.String())
+	writeHeaders(&b, "> ", req.Header)
+	resp, err := rt.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(&b, "! failed after %s: %s\n\n", elapsed, err.Error())
+		writeTrace(b.String())
+		return resp, err
+	}
+	fmt.Fprintf(&b, "< %s in %s\n", resp.Status, elapsed)
+	writeHeaders(&b, "< ", resp.Header)
+	head := make([]byte, traceBodyBytes)
+	n, _ := io.ReadFull(resp.Body, head)
+	head = head[:n]
+	// put back what was read, whoever made the request still wants it
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	fmt.Fprintf(&b, "%s", head)
+	if n == traceBodyBytes {
+		b.WriteString("…(truncated)")
+	}
+	b.WriteString("\n\n")
+	writeTrace(b.String())
+	return resp, nil
+}
+
+func writeHeaders(b *strings.Builder, prefix string, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := strings.Join(h[k], ", ")
+		if k == "Authorization" || k == "Cookie" || k == "Set-Cookie" || k == "X-Sentry-Auth" {
+			v = "[redacted]"
+		}
+		fmt.Fprintf(b, "%s%s: %s\n", prefix, k, v)
+	}
+}
+
+func writeTrace(entry string) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	filename := filepath.Join(cacheDir(), traceFile)
+	if err := os.MkdirAll(cacheDir(), os.ModePerm); err != nil {
+		return
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if fi, err := os.Stat(filename); err == nil && fi.Size() > maxTraceSize {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(filename, flags, 0600)
+	if err != nil {
+		debug("failed to open trace log: %s", err.Error())
+		return
+	}
+	defer f.Close()
+	f.WriteString(entry)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const (
+	trendsURL    = "https://search.itunes.apple.com/WebObjects/MZSearchHints.woa/wa/trends?maxCount=20"
+	trendsFile   = "trending.json"
+	trendsMaxAge = time.Hour * 6
+)
+
+// trendingSearches returns what people are currently searching the store for.
+func trendingSearches(ctx context.Context) ([]string, error) {
+	var terms []string
+	err := cache().LoadOrStoreJSON(trendsFile, trendsMaxAge, func() (interface{}, error) {
+		return fetchTrends(ctx)
+	}, &terms)
+	return terms, err
+}
+
+func fetchTrends(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequest("GET", trendsURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	debug("sending request: %s %s", req.Method, req.URL.String())
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	v, err := decodePlist(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	root, _ := v.(map[string]interface{})
+	list, _ := root["trendingSearches"].([]interface{})
+	var terms []string
+	for _, t := range list {
+		entry, _ := t.(map[string]interface{})
+		if label, ok := entry["label"].(string); ok && label != "" {
+			terms = append(terms, label)
+		}
+	}
+	return terms, nil
+}
+
+// trendingFeedback lists trending searches, each autocompleting (on ↩ or ⇥)
+// into a search for it.
+func trendingFeedback(ctx context.Context, fb *aw.Feedback) error {
+	terms, err := trendingSearches(ctx)
+	if err != nil {
+		return err
+	}
+	for i, t := range terms {
+		fb.NewItem(t).
+			Subtitle(fmt.Sprintf("#%d trending search · ↩ to search", i+1)).
+			Autocomplete(t).
+			Icon(aw.IconClock).
+			Valid(false)
+	}
+	if len(terms) == 0 {
+		fb.NewItem("nothing is trending right now").
+			Icon(aw.IconInfo).
+			Valid(false)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/deanishe/awgo"
+)
+
+func init() {
+	register(command{
+		name:  "trigger",
+		usage: "trigger action=search|lookup [key=value...]",
+		desc:  "structured entry point for external triggers and other workflows",
+		run:   triggerCmd,
+	})
+}
+
+// triggerCmd takes key=value arguments, either as separate arguments or as a
+// single string (which is all an alfred external trigger can pass):
+//
+//	action=search term="screen recorder" [country=gb] [limit=5]
+//		prints script filter json, exactly like the search keyword would
+//	action=lookup id=497799835 [country=gb]
+//		prints the app as alfred variables (and its store url as the arg)
+func triggerCmd(ctx context.Context, args []string) error {
+	params := map[string]string{}
+	for _, tok := range tokenize(strings.Join(args, " ")) {
+		i := strings.IndexByte(tok, '=')
+		if i <= 0 {
+			return fmt.Errorf("expected key=value, got %q", tok)
+		}
+		params[strings.ToLower(tok[:i])] = tok[i+1:]
+	}
+	// overrides are passed on the same way alfred passes settings
+	for param, key := range map[string]string{"country": "COUNTRY", "limit": "RESULT_LIMIT"} {
+		if v, ok := params[param]; ok {
+			os.Setenv(key, v)
+		}
+	}
+	switch params["action"] {
+	case "search":
+		if params["term"] == "" {
+			return fmt.Errorf("action=search needs a term")
+		}
+		forceJSON = true
+		return searchCmd(ctx, []string{params["term"]})
+	case "lookup":
+		id, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil {
+			return fmt.Errorf("action=lookup needs a numeric id")
+		}
+		res, err := lookup(ctx, id)
+		if err != nil {
+			return err
+		}
+		if res == nil {
+			return fmt.Errorf("no app found with id %d", id)
+		}
+		return json.NewEncoder(os.Stdout).Encode(appVars(*res))
+	}
+	return fmt.Errorf("unknown action %q, expected search or lookup", params["action"])
+}
+
+// appVars describes an app as workflow variables, for consumption by the
+// next object in an alfred workflow.
+func appVars(res app) *aw.ArgVars {
+	return aw.NewArgVars().
+		Arg(res.URL).
+		Var("app_id", fmt.Sprint(res.ID)).
+		Var("app_name", res.Name).
+		Var("app_developer", res.Developer).
+		Var("app_price", res.PriceFmt).
+		Var("app_rating", strconv.FormatFloat(res.Rating, 'f', 1, 64)).
+		Var("app_ratings", strconv.Itoa(res.NumRatings)).
+		Var("app_version", res.Version).
+		Var("app_url", res.URL).
+		Var("app_store_url", storeURL(res.ID))
+}
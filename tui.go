@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+func init() {
+	register(command{
+		name:  "tui",
+		usage: "tui [term]",
+		desc:  "search interactively in the terminal, ↑/↓ to pick a result, ↩ to open it",
+		run:   tuiCmd,
+	})
+	commandAliases["--tui"] = "tui"
+}
+
+// tuiDebounce is how long typing has to pause before a search goes out, the
+// rate limiter would not let one through per keystroke anyway.
+const tuiDebounce = time.Millisecond * 250
+
+type tuiResults struct {
+	term string
+	apps []app
+	err  error
+}
+
+// tuiCmd is a small full screen search, drawn with plain ansi escapes on a
+// terminal put in raw mode with stty.
+func tuiCmd(ctx context.Context, args []string) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return fmt.Errorf("tui needs a terminal")
+	}
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	// the alternate screen keeps the shell's scrollback intact
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan []byte)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+
+	var (
+		term     = strings.Join(args, " ")
+		shown    tuiResults
+		selected int
+		status   string
+		results  = make(chan tuiResults, 1)
+		debounce = time.NewTimer(0)
+	)
+	for {
+		tuiDraw(term, shown, selected, status)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-debounce.C:
+			if strings.TrimSpace(term) == "" {
+				shown, status = tuiResults{}, ""
+				continue
+			}
+			status = "searching…"
+			go func(term string) {
+				apps, err := tuiSearch(ctx, term)
+				results <- tuiResults{term, apps, err}
+			}(term)
+		case r := <-results:
+			if r.term != term {
+				// typed on since, a newer search is coming
+				continue
+			}
+			shown, selected, status = r, 0, ""
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch {
+			case string(k) == "\x03" || string(k) == "\x1b":
+				return nil
+			case string(k) == "\x1b[A" || string(k) == "\x10":
+				if selected > 0 {
+					selected--
+				}
+			case string(k) == "\x1b[B" || string(k) == "\x0e":
+				if selected < len(shown.apps)-1 {
+					selected++
+				}
+			case string(k) == "\r":
+				if selected < len(shown.apps) {
+					res := shown.apps[selected]
+					if err := openURL(ctx, primaryURL(res)); err != nil {
+						status = err.Error()
+					} else {
+						status = "opened " + res.Name
+					}
+				}
+			case string(k) == "\x7f" || string(k) == "\b":
+				if term != "" {
+					_, size := utf8.DecodeLastRuneInString(term)
+					term = term[:len(term)-size]
+					debounce.Reset(tuiDebounce)
+				}
+			case string(k) == "\x15":
+				term = ""
+				debounce.Reset(0)
+			case k[0] >= ' ' && k[0] != 0x7f && utf8.Valid(k):
+				term += string(k)
+				debounce.Reset(tuiDebounce)
+			}
+		}
+	}
+}
+
+// tuiSearch runs term through the same pipeline the script filter does.
+func tuiSearch(ctx context.Context, term string) ([]app, error) {
+	q := parseQuery(term)
+	results, err := search(ctx, q)
+	if err != nil && !isRateLimit(err) && err != errOffline && len(results) == 0 {
+		return nil, err
+	}
+	results = q.filter(results)
+	rerank(results)
+	return unblocked(withPins(results, q.term())), err
+}
+
+func tuiDraw(term string, r tuiResults, selected int, status string) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&b, "\x1b[1m🔍 %s\x1b[0m▏\r\n", term)
+	switch {
+	case isRateLimit(r.err) || r.err == errOffline:
+		fmt.Fprintf(&b, "\x1b[33m%s, showing what is cached\x1b[0m\r\n", r.err.Error())
+	case r.err != nil:
+		fmt.Fprintf(&b, "\x1b[31m%s\x1b[0m\r\n", r.err.Error())
+	default:
+		b.WriteString("\r\n")
+	}
+	for i, res := range r.apps {
+		line := fmt.Sprintf(" %s  \x1b[2m%s\x1b[0m", res.Name, subtitle(res))
+		if i == selected {
+			line = fmt.Sprintf("\x1b[7m %s \x1b[0m \x1b[2m%s\x1b[0m", res.Name, subtitle(res))
+		}
+		b.WriteString(line + "\r\n")
+	}
+	if r.term != "" && r.err == nil && len(r.apps) == 0 {
+		b.WriteString("\x1b[2mno results\x1b[0m\r\n")
+	}
+	fmt.Fprintf(&b, "\r\n\x1b[2m%s ↑/↓ select · ↩ open · ⌃U clear · esc quit\x1b[0m", status)
+	fmt.Print(b.String())
+}
+
+// rawTerminal switches the terminal to raw mode and returns a function that
+// puts it back.
+func rawTerminal() (func(), error) {
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = os.Stdin
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(saved) }, nil
+}
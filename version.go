@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+// set at link time, see makefile and package.go
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+func init() {
+	register(command{
+		name:  "version",
+		usage: "version",
+		desc:  "print version information",
+		run: func(ctx context.Context, args []string) error {
+			fmt.Println(versionString())
+			return nil
+		},
+	})
+	commandAliases["--version"] = "version"
+	registerMagic(magicAction{
+		keyword: "version",
+		desc:    "show workflow version",
+		items:   versionMagic,
+	})
+}
+
+func versionString() string {
+	return fmt.Sprintf("%s %s (commit %s, built %s)", binaryName, version, commit, date)
+}
+
+// ldflags returns the linker flags that stamp build info into a binary built
+// from the current checkout.
+func ldflags(ctx context.Context, v string) string {
+	return strings.Join([]string{
+		"-w -s",
+		"-X main.version=" + v,
+		"-X main.commit=" + gitOutput(ctx, "rev-parse", "--short", "HEAD"),
+		"-X main.date=" + buildDate(ctx),
+	}, " ")
+}
+
+// buildDate is SOURCE_DATE_EPOCH, or else when the HEAD commit was made, so
+// that packaging the same commit twice stamps the same date.
+func buildDate(ctx context.Context) string {
+	if s := os.Getenv("SOURCE_DATE_EPOCH"); s != "" {
+		if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(sec, 0).UTC().Format(time.RFC3339)
+		}
+		debug("ignoring SOURCE_DATE_EPOCH %q, it is not a unix time", s)
+	}
+	out := gitOutput(ctx, "log", "-1", "--format=%cI")
+	t, err := time.Parse(time.RFC3339, out)
+	if err != nil {
+		return out
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func gitOutput(ctx context.Context, args ...string) string {
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		debug("git %s failed: %s", strings.Join(args, " "), err.Error())
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func versionMagic() []*aw.Item {
+	return []*aw.Item{
+		new(aw.Item).
+			Title(fmt.Sprintf("%s %s", binaryName, version)).
+			Subtitle(fmt.Sprintf("commit %s, built %s · ⌘C to copy", commit, date)).
+			Copytext(versionString()).
+			Largetype(versionString()).
+			Valid(false),
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBuildDate(t *testing.T) {
+	setenv(t, "SOURCE_DATE_EPOCH", "1700000000")
+	if got, want := buildDate(context.Background()), "2023-11-14T22:13:20Z"; got != want {
+		t.Errorf("buildDate() = %s, want %s", got, want)
+	}
+}
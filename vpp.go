@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+// the content metadata lookup is what mdm servers and apple business manager
+// use to show apps that can be bought in volume. apps missing from it cannot
+// be.
+const (
+	vppLookupURL   = "https://uclient-api.itunes.apple.com/WebObjects/MZStorePlatform.woa/wa/lookup"
+	vppTTL         = time.Hour * 24
+	vppTimeout     = time.Second * 3
+	vppCachePrefix = "vpp-"
+)
+
+func init() {
+	registerAction(action{
+		name: "check-vpp",
+		desc: "Check volume purchase availability",
+		arg:  func(res app) string { return strconv.FormatInt(res.ID, 10) },
+		run:  checkVPP,
+	})
+}
+
+// vppStatus is what apple business manager knows about an app.
+type vppStatus struct {
+	Available bool   `json:"available"`
+	Device    bool   `json:"deviceLicensing"`
+	Price     string `json:"price"`
+}
+
+func (s vppStatus) String() string {
+	if !s.Available {
+		return "not available for volume purchase"
+	}
+	msg := "available for volume purchase"
+	if s.Price != "" {
+		msg += " at " + s.Price
+	}
+	if s.Device {
+		msg += ", can be assigned to devices"
+	} else {
+		msg += ", can only be assigned to users"
+	}
+	return msg
+}
+
+// vppLookup checks whether the app with track id id can be bought through
+// apple business manager in COUNTRY. answers are kept for vppTTL, except
+// when private.
+func vppLookup(ctx context.Context, id int64) (vppStatus, error) {
+	if private() {
+		return fetchVPP(ctx, id)
+	}
+	var status vppStatus
+	key := fmt.Sprintf("%s%s-%d.json", vppCachePrefix, config("COUNTRY"), id)
+	err := cache().LoadOrStoreJSON(key, vppTTL, func() (interface{}, error) {
+		return fetchVPP(ctx, id)
+	}, &status)
+	return status, err
+}
+
+func fetchVPP(ctx context.Context, id int64) (vppStatus, error) {
+	params := url.Values{
+		"version":  {"2"},
+		"id":       {strconv.FormatInt(id, 10)},
+		"p":        {"mdm-lockup"},
+		"caller":   {"MDM"},
+		"platform": {"volumestore"},
+		"cc":       {config("COUNTRY")},
+		"l":        {"en"},
+	}
+	req, err := http.NewRequest("GET", vppLookupURL+"?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return vppStatus{}, err
+	}
+	debug("sending request: %s %s", req.Method, req.URL.String())
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return vppStatus{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return vppStatus{}, fmt.Errorf("non-ok status code returned (%d)", resp.StatusCode)
+	}
+	var body struct {
+		Results map[string]struct {
+			DeviceLicensing bool `json:"isVppDeviceBasedLicenseEnabled"`
+			Offers          []struct {
+				PriceFormatted string `json:"priceFormatted"`
+			} `json:"offers"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return vppStatus{}, err
+	}
+	r, ok := body.Results[strconv.FormatInt(id, 10)]
+	if !ok {
+		return vppStatus{}, nil
+	}
+	status := vppStatus{Available: true, Device: r.DeviceLicensing}
+	if len(r.Offers) > 0 {
+		status.Price = r.Offers[0].PriceFormatted
+	}
+	return status, nil
+}
+
+// checkVPP looks up the app with track id arg and says how it can be bought
+// in volume in a notification. it was asked for, so NOTIFY does not apply.
+func checkVPP(ctx context.Context, arg string) error {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a track id", arg)
+	}
+	status, err := vppLookup(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to check volume purchase availability: %s", err.Error())
+	}
+	name := os.Getenv("app_name")
+	if name == "" {
+		name = arg
+	}
+	return postNotification(ctx, "vpp", name, status.String(), "")
+}
+
+// vppItem is the detail view's line about volume purchasing, with
+// VPP_DETAIL=yes. it is left out when apple business manager does not
+// answer in time.
+func vppItem(ctx context.Context, id int64) *aw.Item {
+	if config("VPP_DETAIL") != "yes" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, vppTimeout)
+	defer cancel()
+	status, err := vppLookup(ctx, id)
+	if err != nil {
+		debug("failed to check volume purchase availability: %s", err.Error())
+		return nil
+	}
+	title := "Volume purchase: no"
+	if status.Available {
+		title = "Volume purchase: yes"
+	}
+	return new(aw.Item).
+		Title(title).
+		Subtitle(status.String() + " (apple business manager, " + config("COUNTRY") + ")").
+		Valid(false)
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo"
+)
+
+const wishlistFile = "wishlist.json"
+
+type wishlistEntry struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Developer string    `json:"developer"`
+	URL       string    `json:"url"`
+	Price     float64   `json:"price"`
+	PriceFmt  string    `json:"formattedPrice"`
+	Version   string    `json:"version"`
+	Rating    float64   `json:"rating,omitempty"`
+	Added     time.Time `json:"added"`
+	Checked   time.Time `json:"checked,omitempty"`
+	// Alert is when a price change is worth a notification, see
+	// priceAlerts. empty means alertDrop
+	Alert string `json:"alert,omitempty"`
+	// Target is the price alertBelow waits for
+	Target float64 `json:"target,omitempty"`
+}
+
+// price alert modes
+const (
+	alertDrop  = "drop"
+	alertFree  = "free"
+	alertBelow = "below"
+)
+
+var priceAlerts = []string{alertDrop, alertFree, alertBelow}
+
+func newWishlistEntry(res app) wishlistEntry {
+	return wishlistEntry{
+		ID:        res.ID,
+		Name:      res.Name,
+		Developer: res.Developer,
+		URL:       res.URL,
+		Price:     res.Price,
+		PriceFmt:  res.PriceFmt,
+		Version:   res.Version,
+		Rating:    res.Rating,
+		Added:     time.Now(),
+	}
+}
+
+func loadWishlist() ([]wishlistEntry, error) {
+	var w []wishlistEntry
+	c := aw.NewCache(dataDir())
+	if !c.Exists(wishlistFile) {
+		return w, nil
+	}
+	return w, c.LoadJSON(wishlistFile, &w)
+}
+
+func saveWishlist(w []wishlistEntry) error {
+	return aw.NewCache(dataDir()).StoreJSON(wishlistFile, w)
+}
+
+// addToWishlist adds apps that are not on the wishlist yet and returns how
+// many were new.
+func addToWishlist(apps []app) (int, error) {
+	w, err := loadWishlist()
+	if err != nil {
+		return 0, err
+	}
+	have := map[int64]bool{}
+	for _, e := range w {
+		have[e.ID] = true
+	}
+	added := 0
+	for _, res := range apps {
+		if have[res.ID] {
+			continue
+		}
+		have[res.ID] = true
+		w = append(w, newWishlistEntry(res))
+		added++
+	}
+	return added, saveWishlist(w)
+}
+
+func init() {
+	registerAction(action{
+		name: "wishlist-free",
+		desc: "Add to wishlist, notify only when it goes free",
+		arg:  func(res app) string { return fmt.Sprint(res.ID) },
+		run: func(ctx context.Context, arg string) error {
+			if err := wishlistAdd(ctx, []string{arg}); err != nil {
+				return err
+			}
+			return wishlistAlert([]string{arg, alertFree})
+		},
+	})
+	registerAction(action{
+		name: "wishlist-add",
+		desc: "Add to wishlist",
+		arg:  func(res app) string { return fmt.Sprint(res.ID) },
+		run: func(ctx context.Context, arg string) error {
+			return wishlistAdd(ctx, []string{arg})
+		},
+	})
+	register(command{
+		name:  "wishlist",
+		usage: "wishlist list|add|remove|alert|export|import|digest|feed",
+		desc:  "manage the wishlist",
+		run:   wishlistCmd,
+	})
+}
+
+func wishlistCmd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch args[0] {
+	case "list":
+		return wishlistExport(append([]string{"-format", "markdown"}, args[1:]...))
+	case "add":
+		return wishlistAdd(ctx, args[1:])
+	case "remove":
+		return wishlistRemove(args[1:])
+	case "alert":
+		return wishlistAlert(args[1:])
+	case "export":
+		return wishlistExport(args[1:])
+	case "import":
+		return wishlistImport(ctx, args[1:])
+	case "digest":
+		return wishlistDigestCmd(args[1:])
+	case "feed":
+		return wishlistFeedCmd(args[1:])
+	}
+	return fmt.Errorf("unknown wishlist command %q", args[0])
+}
+
+func parseIDs(args []string) ([]int64, error) {
+	ids := make([]int64, 0, len(args))
+	for _, a := range args {
+		id, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an app id", a)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func wishlistAdd(ctx context.Context, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	apps, err := lookupMany(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if len(apps) == 0 {
+		return fmt.Errorf("no apps found for %s", strings.Join(args, ", "))
+	}
+	n, err := addToWishlist(apps)
+	debug("added %d apps to the wishlist", n)
+	return err
+}
+
+func wishlistRemove(args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	w, err := loadWishlist()
+	if err != nil {
+		return err
+	}
+	drop := map[int64]bool{}
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := w[:0]
+	for _, e := range w {
+		if !drop[e.ID] {
+			kept = append(kept, e)
+		}
+	}
+	return saveWishlist(kept)
+}
+
+// wishlistAlert sets when an app's price changes are notified about: on any
+// drop, only when it goes free, or when it drops below a target price.
+func wishlistAlert(args []string) error {
+	usage := fmt.Errorf("usage: wishlist alert <id> drop|free|below <price>")
+	if len(args) < 2 {
+		return usage
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not an app id", args[0])
+	}
+	if err := checkOneOf(priceAlerts...)(args[1]); err != nil {
+		return err
+	}
+	var target float64
+	if args[1] == alertBelow {
+		if len(args) != 3 {
+			return usage
+		}
+		target, err = strconv.ParseFloat(strings.TrimLeft(args[2], "$€£¥"), 64)
+		if err != nil || target <= 0 {
+			return fmt.Errorf("%q is not a price", args[2])
+		}
+	} else if len(args) != 2 {
+		return usage
+	}
+	w, err := loadWishlist()
+	if err != nil {
+		return err
+	}
+	for i := range w {
+		if w[i].ID == id {
+			w[i].Alert = args[1]
+			w[i].Target = target
+			return saveWishlist(w)
+		}
+	}
+	return fmt.Errorf("app %d is not on the wishlist", id)
+}
+
+func wishlistExport(args []string) error {
+	fs := flag.NewFlagSet("wishlist export", flag.ContinueOnError)
+	format := fs.String("format", "json", "json or markdown")
+	out := fs.String("o", "", "write to file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	w, err := loadWishlist()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(w); err != nil {
+			return err
+		}
+	case "markdown", "md":
+		buf.WriteString("# wishlist\n\n")
+		for _, e := range w {
+			fmt.Fprintf(&buf, "- [%s](%s) by %s, %s\n", e.Name, e.URL, e.Developer, e.PriceFmt)
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *out == "" {
+		_, err := io.Copy(os.Stdout, &buf)
+		return err
+	}
+	return ioutil.WriteFile(*out, buf.Bytes(), 0644)
+}
+
+var storeURLID = regexp.MustCompile(`/id(\d+)`)
+
+// wishlistImport reads either a json export (a list of entries, or just a list
+// of ids) or plain text with one app per line: an id, a store url, or a name
+// to search for, in which case the top result is taken.
+func wishlistImport(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: wishlist import <file|->")
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if args[0] == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		return err
+	}
+	var (
+		ids   []int64
+		names []string
+	)
+	var entries []struct {
+		ID int64 `json:"id"`
+	}
+	var bare []int64
+	switch {
+	case json.Unmarshal(data, &bare) == nil:
+		ids = bare
+	case json.Unmarshal(data, &entries) == nil:
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+	default:
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			line := strings.TrimSpace(strings.TrimLeft(sc.Text(), "-*• \t"))
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if id, err := strconv.ParseInt(line, 10, 64); err == nil {
+				ids = append(ids, id)
+			} else if m := storeURLID.FindStringSubmatch(line); m != nil {
+				id, _ := strconv.ParseInt(m[1], 10, 64)
+				ids = append(ids, id)
+			} else {
+				names = append(names, line)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return err
+		}
+	}
+	var apps []app
+	if len(ids) > 0 {
+		found, err := lookupMany(ctx, ids)
+		if err != nil {
+			return err
+		}
+		apps = append(apps, found...)
+		if len(found) < len(ids) {
+			fmt.Fprintf(os.Stderr, "%d of %d ids were not found\n", len(ids)-len(found), len(ids))
+		}
+	}
+	for _, name := range names {
+		params := url.Values{}
+		params.Set("media", "software")
+		params.Set("entity", "macSoftware")
+		params.Set("limit", "1")
+		params.Set("country", config("COUNTRY"))
+		params.Set("term", name)
+		found, err := itunes(ctx, "search", params)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			fmt.Fprintf(os.Stderr, "nothing found for %q\n", name)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%q -> %s (%d)\n", name, found[0].Name, found[0].ID)
+		apps = append(apps, found[0])
+	}
+	n, err := addToWishlist(apps)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d new apps\n", n)
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	register(command{
+		name:  "xbar",
+		usage: "xbar [-chart name] [-n count]",
+		desc:  "print a chart in swiftbar/xbar plugin format",
+		run:   xbarCmd,
+	})
+}
+
+// xbarCmd is meant to be wrapped in a plugin script, e.g.
+// ~/Library/Application Support/SwiftBar/Plugins/appstore.1h.sh:
+//
+//	#!/bin/sh
+//	exec /path/to/alfred-apple-app-search xbar -chart top-paid
+func xbarCmd(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("xbar", flag.ContinueOnError)
+	name := fs.String("chart", "top-free", "chart to show: "+strings.Join(chartNames, ", "))
+	n := fs.Int("n", 15, "number of apps to show")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	entries, err := chart(ctx, *name, 0)
+	if err != nil {
+		// still render something clickable, a plugin that prints nothing
+		// just vanishes from the menubar
+		fmt.Printf("🛍 ⚠︎\n---\n%s | color=red\n", xbarEscape(err.Error()))
+		return nil
+	}
+	if len(entries) > *n {
+		entries = entries[:*n]
+	}
+	fmt.Println("🛍")
+	fmt.Println("---")
+	fmt.Printf("%s Mac Apps (%s) | size=11\n", charts[*name].title, strings.ToUpper(config("COUNTRY")))
+	for _, e := range entries {
+		fmt.Printf(
+			"%d. %s — %s | href=%s\n",
+			e.Rank,
+			xbarEscape(e.Name),
+			xbarEscape(e.Price),
+			storeURL(e.ID),
+		)
+		fmt.Printf("--%s | href=%s\n", xbarEscape(e.Developer), e.URL)
+	}
+	fmt.Println("---")
+	fmt.Println("Refresh | refresh=true")
+	return nil
+}
+
+// xbarEscape keeps text from being read as plugin syntax.
+func xbarEscape(s string) string {
+	return strings.NewReplacer("|", "¦", "\n", " ").Replace(s)
+}